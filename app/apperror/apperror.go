@@ -0,0 +1,140 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error is an error that can be shown to the client. Code is a stable machine readable identifier clients can
+// switch on, Status is the HTTP status it is answered with and Message is meant for the user. The cause is only
+// logged, it never reaches the client.
+type Error struct {
+	Code       string
+	Status     int
+	Message    string
+	RetryAfter time.Duration
+
+	cause error
+}
+
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors by Code so copies made by Wrap, WithMessage and WithRetryAfter still match their origin
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e caused by cause
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.cause = cause
+	return &c
+}
+
+// WithMessage returns a copy of e shown to the user with message
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithRetryAfter returns a copy of e telling the client to wait retryAfter before trying again
+func (e *Error) WithRetryAfter(retryAfter time.Duration) *Error {
+	c := *e
+	c.RetryAfter = retryAfter
+	return &c
+}
+
+var (
+	ErrValidation         = New("invalid_request", http.StatusBadRequest, "request is not valid")
+	ErrUnauthorized       = New("unauthorized", http.StatusUnauthorized, "unauthorized")
+	ErrInvalidToken       = New("invalid_token", http.StatusUnauthorized, "invalid token")
+	ErrForbidden          = New("forbidden", http.StatusForbidden, "forbidden")
+	ErrNotFound           = New("not_found", http.StatusNotFound, "not found")
+	ErrConflict           = New("conflict", http.StatusConflict, "conflict")
+	ErrInternal           = New("internal_error", http.StatusInternalServerError, "internal error")
+	ErrServiceUnavailable = New("service_unavailable", http.StatusServiceUnavailable, "service unavailable")
+
+	ErrUserAlreadyExists        = New("user_already_exists", http.StatusConflict, "user already exists")
+	ErrUserNotFound             = New("user_not_found", http.StatusNotFound, "user not found")
+	ErrInvalidCredentials       = New("invalid_credentials", http.StatusBadRequest, "invalid email or password")
+	ErrTooManyLoginAttempts     = New("too_many_login_attempts", http.StatusTooManyRequests, "too many login attempts")
+	ErrInvalidRefreshToken      = New("invalid_refresh_token", http.StatusUnauthorized, "invalid refresh token")
+	ErrInvalidResetToken        = New("invalid_reset_token", http.StatusBadRequest, "invalid or expired reset token")
+	ErrInvalidVerificationToken = New("invalid_verification_token", http.StatusBadRequest, "invalid or expired verification token")
+	ErrEmailNotVerified         = New("email_not_verified", http.StatusForbidden, "email is not verified")
+
+	ErrProfileNotViewed     = New("profile_not_viewed", http.StatusBadRequest, "profile has not been viewed")
+	ErrSwipeQuotaExceeded   = New("swipe_quota_exceeded", http.StatusTooManyRequests, "already used up all swipe quota")
+	ErrInvalidTimezone      = New("invalid_timezone", http.StatusBadRequest, "timezone is not valid")
+	ErrMatchNotFound        = New("match_not_found", http.StatusNotFound, "match not found")
+	ErrNotMatched           = New("not_matched", http.StatusForbidden, "users are not matched")
+	ErrLikeNotFound         = New("like_not_found", http.StatusNotFound, "like not found")
+	ErrSubscriptionRequired = New("subscription_required", http.StatusForbidden, "subscription required")
+
+	ErrProductNotFound     = New("product_not_found", http.StatusBadRequest, "product not found")
+	ErrGiftToSelf          = New("gift_to_self", http.StatusBadRequest, "cannot gift to yourself")
+	ErrRecipientNotFound   = New("recipient_not_found", http.StatusNotFound, "recipient not found")
+	ErrOrderNotFound       = New("order_not_found", http.StatusNotFound, "order not found")
+	ErrOrderStatusConflict = New("order_status_conflict", http.StatusConflict, "order status conflict")
+	ErrInvalidSignature    = New("invalid_signature", http.StatusUnauthorized, "invalid signature")
+
+	ErrIdempotencyKeyInUse  = New("idempotency_key_in_use", http.StatusConflict, "request with the same idempotency key is in progress")
+	ErrIdempotencyKeyReused = New("idempotency_key_reused", http.StatusUnprocessableEntity, "idempotency key was used with a different request")
+)
+
+// As returns err as an *Error, errors that aren't one are internal errors caused by err
+func As(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal.Wrap(err)
+}
+
+// FromHTTPStatus returns the error answered with status, used for errors raised by echo itself such as
+// unknown routes or malformed bodies
+func FromHTTPStatus(status int, message string) *Error {
+	var appErr *Error
+	switch status {
+	case http.StatusBadRequest:
+		appErr = ErrValidation
+	case http.StatusUnauthorized:
+		appErr = ErrUnauthorized
+	case http.StatusForbidden:
+		appErr = ErrForbidden
+	case http.StatusNotFound:
+		appErr = ErrNotFound
+	case http.StatusInternalServerError:
+		return ErrInternal
+	case http.StatusServiceUnavailable:
+		appErr = ErrServiceUnavailable
+	default:
+		text := http.StatusText(status)
+		if text == "" {
+			return ErrInternal
+		}
+		appErr = New(strings.ReplaceAll(strings.ToLower(text), " ", "_"), status, strings.ToLower(text))
+	}
+
+	if message != "" {
+		appErr = appErr.WithMessage(message)
+	}
+	return appErr
+}
@@ -0,0 +1,31 @@
+package apperror
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// codeInvalidToken is returned by auth service for tokens it doesn't know, outside the standard gRPC codes
+const codeInvalidToken codes.Code = 40
+
+// grpcErrors maps the status codes returned by user and auth service, codes not listed are internal errors
+var grpcErrors = map[codes.Code]*Error{
+	codes.InvalidArgument:   ErrValidation,
+	codes.NotFound:          ErrNotFound,
+	codes.AlreadyExists:     ErrConflict,
+	codes.PermissionDenied:  ErrForbidden,
+	codes.Unauthenticated:   ErrUnauthorized,
+	codeInvalidToken:        ErrUnauthorized,
+	codes.Unavailable:       ErrServiceUnavailable,
+	codes.DeadlineExceeded:  ErrServiceUnavailable,
+	codes.ResourceExhausted: ErrServiceUnavailable,
+}
+
+// FromGRPC returns the error matching the status code of err returned by a gRPC call, caused by err
+func FromGRPC(err error) *Error {
+	appErr, ok := grpcErrors[status.Code(err)]
+	if !ok {
+		appErr = ErrInternal
+	}
+	return appErr.Wrap(err)
+}
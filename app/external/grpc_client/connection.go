@@ -0,0 +1,103 @@
+package grpc_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/atrariksa/kenalan-core/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer/leastrequest"
+	"google.golang.org/grpc/balancer/pickfirst"
+	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/connectivity"
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/keepalive"
+)
+
+// NewConnection creates the long-lived connection to a downstream service, it is shared by every request and
+// reconnects by itself when the service goes away. The connection is established in the background so a service
+// that is down at startup doesn't keep core from starting, calls fail until it is reachable.
+//
+// Calls are balanced over every address of the service, the configured endpoints or else every address host
+// resolves to. With health checking, instances reporting not serving through the gRPC health protocol are
+// skipped, instances that don't implement it are assumed healthy.
+func NewConnection(name string, cfg config.UserServerConfig) (*grpc.ClientConn, error) {
+	serviceConfig, err := ServiceConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s service config: %w", name, err)
+	}
+
+	transportCredentials, err := NewTransportCredentials(cfg.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("%s credentials: %w", name, err)
+	}
+
+	conn, err := grpc.NewClient(
+		Target(cfg),
+		grpc.WithResolvers(StaticResolverBuilder{}),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    cfg.KeepaliveTime,
+			Timeout: cfg.KeepaliveTimeout,
+			// idle connections aren't pinged, services reject that under their default EnforcementPolicy
+			PermitWithoutStream: false,
+		}),
+		grpc.WithUnaryInterceptor(ResilienceInterceptor(cfg)))
+	if err != nil {
+		return nil, fmt.Errorf("create %s connection: %w", name, err)
+	}
+
+	conn.Connect()
+	go MonitorConnection(context.Background(), name, conn)
+	return conn, nil
+}
+
+// Target is the static list of endpoints when there is one, otherwise host is looked up in DNS
+func Target(cfg config.UserServerConfig) string {
+	if len(cfg.Endpoints) > 0 {
+		return fmt.Sprintf("%s:///%s", StaticScheme, strings.Join(cfg.Endpoints, ","))
+	}
+	return fmt.Sprintf("dns:///%v:%v", cfg.Host, cfg.Port)
+}
+
+// ServiceConfig picks the load balancing policy and health checking of cfg
+func ServiceConfig(cfg config.UserServerConfig) (string, error) {
+	policy, ok := loadBalancingPolicies[cfg.LoadBalancing]
+	if !ok {
+		return "", fmt.Errorf("unknown load balancing %q", cfg.LoadBalancing)
+	}
+
+	serviceConfig := map[string]any{
+		"loadBalancingConfig": []map[string]any{{policy: map[string]any{}}},
+	}
+	if cfg.HealthCheck {
+		serviceConfig["healthCheckConfig"] = map[string]any{"serviceName": cfg.HealthCheckService}
+	}
+	b, err := json.Marshal(serviceConfig)
+	return string(b), err
+}
+
+// loadBalancingPolicies maps the config names to the registered gRPC balancers, health checking only applies to
+// round_robin and least_request
+var loadBalancingPolicies = map[string]string{
+	"pick_first":    pickfirst.Name,
+	"round_robin":   roundrobin.Name,
+	"least_request": leastrequest.Name,
+}
+
+// MonitorConnection logs every state change of conn until ctx is done or conn is closed
+func MonitorConnection(ctx context.Context, name string, conn *grpc.ClientConn) {
+	state := conn.GetState()
+	log.Printf("%s connection is %v", name, state)
+	for state != connectivity.Shutdown {
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+		state = conn.GetState()
+		log.Printf("%s connection is %v", name, state)
+	}
+}
@@ -0,0 +1,185 @@
+package grpc_client
+
+import (
+	"context"
+	"math/rand"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen is returned without calling the service while its circuit breaker is open
+var ErrCircuitOpen = status.Error(codes.Unavailable, "circuit breaker is open")
+
+// IdempotentMethods can be retried safely, other calls are never sent twice
+var IdempotentMethods = map[string]bool{
+	AuthService_IsTokenValid_FullMethodName:            true,
+	UserService_GetUserByEmail_FullMethodName:          true,
+	UserService_GetNextProfileExceptIDs_FullMethodName: true,
+}
+
+// ResilienceInterceptor bounds every call by its deadline from cfg, retries idempotent calls that failed
+// transiently with jittered exponential backoff and fails fast while the circuit breaker of the service is open.
+// The deadline applies to each attempt and is cut to what remains of the caller's deadline, which bounds the call
+// as a whole including its retries.
+func ResilienceInterceptor(cfg config.UserServerConfig) grpc.UnaryClientInterceptor {
+	breaker := NewCircuitBreaker(cfg.CircuitBreaker)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		deadline := methodDeadline(cfg, method)
+		maxAttempts := 1
+		if IdempotentMethods[method] && cfg.Retry.MaxAttempts > 1 {
+			maxAttempts = cfg.Retry.MaxAttempts
+		}
+
+		var err error
+		for attempt := 0; attempt < maxAttempts; attempt++ {
+			if attempt > 0 {
+				err = sleep(ctx, backoff(cfg.Retry, attempt))
+				if err != nil {
+					return status.FromContextError(err).Err()
+				}
+			}
+
+			isAllowed, isProbe := breaker.Allow()
+			if !isAllowed {
+				return ErrCircuitOpen
+			}
+			err = invokeWithDeadline(ctx, deadline, method, req, reply, cc, invoker, opts...)
+			// a call cut short by the caller says nothing about the service
+			if ctx.Err() != nil {
+				breaker.Release(isProbe)
+				return err
+			}
+			breaker.Record(err, isProbe)
+			if !isTransient(err) {
+				return err
+			}
+		}
+		return err
+	}
+}
+
+// invokeWithDeadline makes one attempt within deadline, or within the caller's deadline when that comes first
+func invokeWithDeadline(ctx context.Context, deadline time.Duration, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// methodDeadline is the deadline configured for the method, keys are lower case since config keys are
+// case insensitive
+func methodDeadline(cfg config.UserServerConfig, method string) time.Duration {
+	if deadline, ok := cfg.Deadlines[strings.ToLower(path.Base(method))]; ok {
+		return deadline
+	}
+	return cfg.Deadline
+}
+
+// backoff picks a random delay up to base*2^(attempt-1) capped at max, so clients retrying together spread out
+func backoff(cfg config.RetryConfig, attempt int) time.Duration {
+	delay := cfg.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > cfg.MaxDelay {
+		delay = cfg.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isTransient tells whether err means the service is down or overloaded rather than that the call was refused
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// CircuitBreaker opens after FailureThreshold consecutive transient failures and rejects calls for OpenTimeout.
+// Then a single call is let through, the breaker closes when it succeeds and opens again when it fails.
+type CircuitBreaker struct {
+	Cfg config.CircuitBreakerConfig
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func NewCircuitBreaker(cfg config.CircuitBreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{Cfg: cfg}
+}
+
+// Allow tells whether a call may be made now and whether it is the single call probing an open breaker.
+// The probe flag is handed back to Release or Record.
+func (cb *CircuitBreaker) Allow() (bool, bool) {
+	if cb.Cfg.FailureThreshold <= 0 {
+		return true, false
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.failures < cb.Cfg.FailureThreshold {
+		return true, false
+	}
+	if time.Now().Before(cb.openUntil) || cb.probing {
+		return false, false
+	}
+	cb.probing = true
+	return true, true
+}
+
+// Release gives back an allowed call whose outcome doesn't count, only the probe lets another probe through
+func (cb *CircuitBreaker) Release(isProbe bool) {
+	if !isProbe {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.probing = false
+}
+
+// Record counts the outcome of an allowed call. A call allowed before the breaker opened doesn't end the probe.
+func (cb *CircuitBreaker) Record(err error, isProbe bool) {
+	if cb.Cfg.FailureThreshold <= 0 {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if isProbe {
+		cb.probing = false
+	}
+	if !isTransient(err) {
+		// closed again, a probe still in flight counts like any other call
+		cb.failures = 0
+		cb.probing = false
+		return
+	}
+	cb.failures++
+	if cb.failures >= cb.Cfg.FailureThreshold {
+		cb.openUntil = time.Now().Add(cb.Cfg.OpenTimeout)
+	}
+}
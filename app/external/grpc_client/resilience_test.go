@@ -0,0 +1,47 @@
+package grpc_client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	cb := NewCircuitBreaker(config.CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Millisecond})
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	// a call allowed while closed is still in flight when the breaker opens
+	_, stragglerIsProbe := cb.Allow()
+	for i := 0; i < 2; i++ {
+		_, isProbe := cb.Allow()
+		cb.Record(unavailable, isProbe)
+	}
+	if isAllowed, _ := cb.Allow(); isAllowed {
+		t.Fatal("open breaker allowed a call")
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	isAllowed, isProbe := cb.Allow()
+	if !isAllowed || !isProbe {
+		t.Fatalf("got allowed %v probe %v, want the probe let through", isAllowed, isProbe)
+	}
+
+	// the straggler ending, cut short or failed, doesn't let another probe through
+	cb.Release(stragglerIsProbe)
+	if isAllowed, _ := cb.Allow(); isAllowed {
+		t.Fatal("second probe allowed after a straggler was released")
+	}
+	cb.Record(unavailable, stragglerIsProbe)
+	time.Sleep(2 * time.Millisecond)
+	if isAllowed, _ := cb.Allow(); isAllowed {
+		t.Fatal("second probe allowed after a straggler failed")
+	}
+
+	cb.Record(nil, isProbe)
+	if isAllowed, isProbe := cb.Allow(); !isAllowed || isProbe {
+		t.Fatalf("got allowed %v probe %v, want the breaker closed", isAllowed, isProbe)
+	}
+}
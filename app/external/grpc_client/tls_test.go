@@ -0,0 +1,297 @@
+package grpc_client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a leaf certificate for name, a host name or an IP address, in PEM with its key
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{name}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) serverCert(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, name, x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// testServer is a gRPC health server whose certificate can be swapped while it runs
+type testServer struct {
+	addr string
+
+	mu   sync.Mutex
+	cert tls.Certificate
+}
+
+func (ts *testServer) setCert(cert tls.Certificate) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.cert = cert
+}
+
+// startTestServer serves TLS with cert, requiring a client certificate issued by clientCA when it is set
+func startTestServer(t *testing.T, cert tls.Certificate, clientCA *testCA) *testServer {
+	t.Helper()
+	ts := &testServer{cert: cert}
+	tlsConfig := &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			ts.mu.Lock()
+			defer ts.mu.Unlock()
+			return &ts.cert, nil
+		},
+	}
+	if clientCA != nil {
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(clientCA.cert)
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	ts.addr = lis.Addr().String()
+	return ts
+}
+
+func writeFile(t *testing.T, path string, data []byte) string {
+	t.Helper()
+	err := os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkHealth opens a new connection to addr with creds and calls the health service over it
+func checkHealth(t *testing.T, addr string, creds credentials.TransportCredentials) error {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	return err
+}
+
+func newTestCredentials(t *testing.T, cfg config.TLSConfig) credentials.TransportCredentials {
+	t.Helper()
+	cfg.Enabled = true
+	creds, err := NewTransportCredentials(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return creds
+}
+
+func TestTransportCredentialsTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test ca")
+	otherCA := newTestCA(t, "other ca")
+	server := startTestServer(t, ca.serverCert(t, "user-service"), nil)
+
+	tests := []struct {
+		name    string
+		cfg     config.TLSConfig
+		wantErr bool
+	}{
+		{
+			name: "trusted server",
+			cfg:  config.TLSConfig{CAFile: writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem), ServerName: "user-service"},
+		},
+		{
+			name:    "server signed by another ca",
+			cfg:     config.TLSConfig{CAFile: writeFile(t, filepath.Join(dir, "other-ca.pem"), otherCA.pem), ServerName: "user-service"},
+			wantErr: true,
+		},
+		{
+			name:    "hostname mismatch",
+			cfg:     config.TLSConfig{CAFile: filepath.Join(dir, "ca.pem"), ServerName: "auth-service"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHealth(t, server.addr, newTestCredentials(t, tt.cfg))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransportCredentialsIPEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test ca")
+	caFile := writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+
+	tests := []struct {
+		name       string
+		serverName string
+		wantErr    bool
+	}{
+		{name: "certificate for the ip", serverName: "127.0.0.1"},
+		{name: "certificate for another host", serverName: "user-service", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// dialed by IP without server-name, no SNI is sent so the IP itself must be verified
+			server := startTestServer(t, ca.serverCert(t, tt.serverName), nil)
+			err := checkHealth(t, server.addr, newTestCredentials(t, config.TLSConfig{CAFile: caFile}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransportCredentialsMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test ca")
+	server := startTestServer(t, ca.serverCert(t, "user-service"), ca)
+	caFile := writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+	certPEM, keyPEM := ca.issue(t, "kenalan-core", x509.ExtKeyUsageClientAuth)
+	certFile := writeFile(t, filepath.Join(dir, "client.pem"), certPEM)
+	keyFile := writeFile(t, filepath.Join(dir, "client-key.pem"), keyPEM)
+
+	t.Run("with client certificate", func(t *testing.T) {
+		creds := newTestCredentials(t, config.TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile, ServerName: "user-service"})
+		err := checkHealth(t, server.addr, creds)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("without client certificate", func(t *testing.T) {
+		creds := newTestCredentials(t, config.TLSConfig{CAFile: caFile, ServerName: "user-service"})
+		err := checkHealth(t, server.addr, creds)
+		if err == nil {
+			t.Fatal("server accepted a client without certificate")
+		}
+	})
+}
+
+func TestTransportCredentialsReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test ca")
+	server := startTestServer(t, ca.serverCert(t, "user-service"), nil)
+	caFile := writeFile(t, filepath.Join(dir, "ca.pem"), ca.pem)
+	creds := newTestCredentials(t, config.TLSConfig{CAFile: caFile, ServerName: "user-service"})
+
+	err := checkHealth(t, server.addr, creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the service moves to a certificate of a new CA, connections fail until the bundle is rotated too
+	rotatedCA := newTestCA(t, "rotated ca")
+	server.setCert(rotatedCA.serverCert(t, "user-service"))
+	err = checkHealth(t, server.addr, creds)
+	if err == nil {
+		t.Fatal("server certificate of the new CA accepted before rotating the bundle")
+	}
+
+	writeFile(t, caFile, rotatedCA.pem)
+	modTime := time.Now().Add(time.Second)
+	err = os.Chtimes(caFile, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = checkHealth(t, server.addr, creds)
+	if err != nil {
+		t.Fatalf("rotated CA not picked up: %v", err)
+	}
+}
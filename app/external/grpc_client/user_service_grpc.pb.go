@@ -25,6 +25,7 @@ const (
 	UserService_GetUserSubscription_FullMethodName     = "/grpc_client.UserService/GetUserSubscription"
 	UserService_GetNextProfileExceptIDs_FullMethodName = "/grpc_client.UserService/GetNextProfileExceptIDs"
 	UserService_UpsertSubscription_FullMethodName      = "/grpc_client.UserService/UpsertSubscription"
+	UserService_UpdatePassword_FullMethodName          = "/grpc_client.UserService/UpdatePassword"
 )
 
 // UserServiceClient is the client API for UserService service.
@@ -37,6 +38,7 @@ type UserServiceClient interface {
 	GetUserSubscription(ctx context.Context, in *GetUserSubscriptionRequest, opts ...grpc.CallOption) (*GetUserSubscriptionResponse, error)
 	GetNextProfileExceptIDs(ctx context.Context, in *GetNextProfileExceptIDsRequest, opts ...grpc.CallOption) (*GetNextProfileExceptIDsResponse, error)
 	UpsertSubscription(ctx context.Context, in *UpsertSubscriptionRequest, opts ...grpc.CallOption) (*UpsertSubscriptionResponse, error)
+	UpdatePassword(ctx context.Context, in *UpdatePasswordRequest, opts ...grpc.CallOption) (*UpdatePasswordResponse, error)
 }
 
 type userServiceClient struct {
@@ -107,6 +109,16 @@ func (c *userServiceClient) UpsertSubscription(ctx context.Context, in *UpsertSu
 	return out, nil
 }
 
+func (c *userServiceClient) UpdatePassword(ctx context.Context, in *UpdatePasswordRequest, opts ...grpc.CallOption) (*UpdatePasswordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdatePasswordResponse)
+	err := c.cc.Invoke(ctx, UserService_UpdatePassword_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserServiceServer is the server API for UserService service.
 // All implementations must embed UnimplementedUserServiceServer
 // for forward compatibility
@@ -117,6 +129,7 @@ type UserServiceServer interface {
 	GetUserSubscription(context.Context, *GetUserSubscriptionRequest) (*GetUserSubscriptionResponse, error)
 	GetNextProfileExceptIDs(context.Context, *GetNextProfileExceptIDsRequest) (*GetNextProfileExceptIDsResponse, error)
 	UpsertSubscription(context.Context, *UpsertSubscriptionRequest) (*UpsertSubscriptionResponse, error)
+	UpdatePassword(context.Context, *UpdatePasswordRequest) (*UpdatePasswordResponse, error)
 	mustEmbedUnimplementedUserServiceServer()
 }
 
@@ -142,6 +155,9 @@ func (UnimplementedUserServiceServer) GetNextProfileExceptIDs(context.Context, *
 func (UnimplementedUserServiceServer) UpsertSubscription(context.Context, *UpsertSubscriptionRequest) (*UpsertSubscriptionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpsertSubscription not implemented")
 }
+func (UnimplementedUserServiceServer) UpdatePassword(context.Context, *UpdatePasswordRequest) (*UpdatePasswordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePassword not implemented")
+}
 func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
 
 // UnsafeUserServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -263,6 +279,24 @@ func _UserService_UpsertSubscription_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_UpdatePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).UpdatePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: UserService_UpdatePassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).UpdatePassword(ctx, req.(*UpdatePasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -294,6 +328,10 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpsertSubscription",
 			Handler:    _UserService_UpsertSubscription_Handler,
 		},
+		{
+			MethodName: "UpdatePassword",
+			Handler:    _UserService_UpdatePassword_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "user_service.proto",
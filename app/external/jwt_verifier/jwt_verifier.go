@@ -0,0 +1,318 @@
+package jwt_verifier
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNotJWT is returned for tokens that are not JWTs, those have to be checked by auth service
+var ErrNotJWT = errors.New("token is not a jwt")
+
+// ErrUnknownKey is returned when the token is signed by a key that is not (yet) known locally
+var ErrUnknownKey = errors.New("token signing key is unknown")
+
+// ErrInvalidJWT is returned for JWTs that fail verification, there is no point asking auth service about them
+var ErrInvalidJWT = errors.New("token is not valid")
+
+// minReloadInterval limits how often an unknown kid triggers reloading the key set
+const minReloadInterval = time.Minute
+
+type Claims struct {
+	Subject   string
+	Email     string
+	ID        string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+type IJWTVerifier interface {
+	Verify(token string) (Claims, error)
+}
+
+// KeySetVerifier verifies asymmetrically signed JWTs against a PEM public key or a JWKS loaded from a file
+// or URL. Keys are reloaded when a token refers to a kid that is not in the loaded set.
+type KeySetVerifier struct {
+	Cfg    config.JWTConfig
+	Client *http.Client
+
+	mu       sync.RWMutex
+	keys     map[string]crypto.PublicKey
+	loadedAt time.Time
+}
+
+// NewKeySetVerifier returns nil when no key source is configured, every token is then left to auth service
+func NewKeySetVerifier(cfg config.JWTConfig) (*KeySetVerifier, error) {
+	if cfg.PublicKeyFile == "" && cfg.JWKSFile == "" && cfg.JWKSURL == "" {
+		return nil, nil
+	}
+
+	kv := &KeySetVerifier{
+		Cfg:    cfg,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+	err := kv.reload()
+	if err != nil {
+		return nil, err
+	}
+	return kv, nil
+}
+
+func (kv *KeySetVerifier) Verify(token string) (Claims, error) {
+	var claims Claims
+	if strings.Count(token, ".") != 2 {
+		return claims, ErrNotJWT
+	}
+
+	// tokens without expiry are not accepted locally
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+	}
+	if kv.Cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(kv.Cfg.Issuer))
+	}
+	if kv.Cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(kv.Cfg.Audience))
+	}
+
+	mapClaims := jwt.MapClaims{}
+	_, err := jwt.NewParser(options...).ParseWithClaims(token, mapClaims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return kv.key(kid)
+	})
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return claims, ErrUnknownKey
+		}
+		if errors.Is(err, jwt.ErrTokenMalformed) {
+			return claims, ErrNotJWT
+		}
+		return claims, ErrInvalidJWT
+	}
+
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.Email, _ = mapClaims[kv.Cfg.EmailClaim].(string)
+	claims.ID, _ = mapClaims["jti"].(string)
+	if iat, err := mapClaims.GetIssuedAt(); err == nil && iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+	if claims.Email == "" {
+		return claims, ErrInvalidJWT
+	}
+
+	return claims, nil
+}
+
+func (kv *KeySetVerifier) key(kid string) (crypto.PublicKey, error) {
+	kv.mu.RLock()
+	key, ok := kv.lookup(kid)
+	loadedAt := kv.loadedAt
+	kv.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(loadedAt) < minReloadInterval {
+		return nil, ErrUnknownKey
+	}
+	err := kv.reload()
+	if err != nil {
+		return nil, ErrUnknownKey
+	}
+
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+	key, ok = kv.lookup(kid)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// lookup must be called holding mu. A single PEM key is used whatever the kid, a token without kid
+// is accepted only when the key set has exactly one key.
+func (kv *KeySetVerifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if kv.Cfg.PublicKeyFile != "" || (kid == "" && len(kv.keys) == 1) {
+		for _, key := range kv.keys {
+			return key, true
+		}
+	}
+	key, ok := kv.keys[kid]
+	return key, ok
+}
+
+func (kv *KeySetVerifier) reload() error {
+	keys := map[string]crypto.PublicKey{}
+	var err error
+	switch {
+	case kv.Cfg.PublicKeyFile != "":
+		keys[""], err = readPEMPublicKey(kv.Cfg.PublicKeyFile)
+	case kv.Cfg.JWKSFile != "":
+		var data []byte
+		data, err = os.ReadFile(kv.Cfg.JWKSFile)
+		if err == nil {
+			keys, err = parseJWKS(data)
+		}
+	default:
+		var data []byte
+		data, err = kv.fetchJWKS()
+		if err == nil {
+			keys, err = parseJWKS(data)
+		}
+	}
+
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.loadedAt = time.Now()
+	if err != nil {
+		return err
+	}
+	kv.keys = keys
+	return nil
+}
+
+func (kv *KeySetVerifier) fetchJWKS() ([]byte, error) {
+	resp, err := kv.Client.Get(kv.Cfg.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func readPEMPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(data); err == nil {
+		return key, nil
+	}
+	return jwt.ParseEdPublicKeyFromPEM(data)
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS keeps the signing keys of a JWKS document by kid, keys of unsupported types are skipped
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set jwks
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no usable signing key")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("ec point is not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,197 @@
+package jwt_verifier
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// writeJWKS writes a JWKS holding the public half of key under kid
+func writeJWKS(t *testing.T, path string, kid string, key *rsa.PrivateKey) string {
+	t.Helper()
+	data, _ := json.Marshal(jwks{Keys: []jwk{{
+		Kid: kid,
+		Kty: "RSA",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	err := os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writePublicKeyPEM writes the public half of key as PEM and returns its bytes
+func writePublicKeyPEM(t *testing.T, path string, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestKeySetVerifier(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t)
+	otherKey := newTestKey(t)
+	publicKeyPEM := writePublicKeyPEM(t, filepath.Join(dir, "public.pem"), key)
+
+	cfg := config.JWTConfig{Issuer: "kenalan-auth", Audience: "kenalan-core", EmailClaim: "email"}
+	jwksCfg := cfg
+	jwksCfg.JWKSFile = writeJWKS(t, filepath.Join(dir, "jwks.json"), "k1", key)
+	jwksVerifier, err := NewKeySetVerifier(jwksCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemCfg := cfg
+	pemCfg.PublicKeyFile = filepath.Join(dir, "public.pem")
+	pemVerifier, err := NewKeySetVerifier(pemCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	claims := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":   "1",
+			"email": "a@b.c",
+			"jti":   "token-1",
+			"iss":   "kenalan-auth",
+			"aud":   "kenalan-core",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		verifier *KeySetVerifier
+		token    string
+		wantErr  error
+	}{
+		{
+			name:     "valid token",
+			verifier: jwksVerifier,
+			token:    sign(t, jwt.SigningMethodRS256, "k1", claims(nil), key),
+		},
+		{
+			name:     "valid token against a pem key",
+			verifier: pemVerifier,
+			token:    sign(t, jwt.SigningMethodRS256, "", claims(nil), key),
+		},
+		{
+			name:     "wrong issuer",
+			verifier: jwksVerifier,
+			token:    sign(t, jwt.SigningMethodRS256, "k1", claims(func(c jwt.MapClaims) { c["iss"] = "someone-else" }), key),
+			wantErr:  ErrInvalidJWT,
+		},
+		{
+			name:     "wrong audience",
+			verifier: jwksVerifier,
+			token:    sign(t, jwt.SigningMethodRS256, "k1", claims(func(c jwt.MapClaims) { c["aud"] = "another-service" }), key),
+			wantErr:  ErrInvalidJWT,
+		},
+		{
+			name:     "expired token",
+			verifier: jwksVerifier,
+			token:    sign(t, jwt.SigningMethodRS256, "k1", claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }), key),
+			wantErr:  ErrInvalidJWT,
+		},
+		{
+			name:     "token without expiry",
+			verifier: jwksVerifier,
+			token:    sign(t, jwt.SigningMethodRS256, "k1", claims(func(c jwt.MapClaims) { delete(c, "exp") }), key),
+			wantErr:  ErrInvalidJWT,
+		},
+		{
+			name:     "signed by another key",
+			verifier: jwksVerifier,
+			token:    sign(t, jwt.SigningMethodRS256, "k1", claims(nil), otherKey),
+			wantErr:  ErrInvalidJWT,
+		},
+		{
+			name:     "unknown kid",
+			verifier: jwksVerifier,
+			token:    sign(t, jwt.SigningMethodRS256, "k2", claims(nil), otherKey),
+			wantErr:  ErrUnknownKey,
+		},
+		{
+			name:     "alg none",
+			verifier: jwksVerifier,
+			token:    sign(t, jwt.SigningMethodNone, "k1", claims(nil), jwt.UnsafeAllowNoneSignatureType),
+			wantErr:  ErrInvalidJWT,
+		},
+		{
+			name:     "hs256 with the public key as secret",
+			verifier: pemVerifier,
+			token:    sign(t, jwt.SigningMethodHS256, "", claims(nil), publicKeyPEM),
+			wantErr:  ErrInvalidJWT,
+		},
+		{
+			name:     "opaque token",
+			verifier: jwksVerifier,
+			token:    "d3b07384d113edec49eaa6238ad5ff00",
+			wantErr:  ErrNotJWT,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.verifier.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.Email != "a@b.c" || got.Subject != "1" || got.ID != "token-1" || got.ExpiresAt.Unix() != now.Add(time.Hour).Unix() {
+				t.Fatalf("got claims %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/model"
+)
+
+// FileMailer appends mails to a file instead of sending them, for local development
+type FileMailer struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{
+		Path: path,
+	}
+}
+
+func (fm *FileMailer) Send(ctx context.Context, mail model.Mail) error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	f, err := os.OpenFile(fm.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), mail.To, mail.Subject, mail.Body)
+	return err
+}
+
+// MemoryMailer keeps sent mails in memory so tests can read them back
+type MemoryMailer struct {
+	mu    sync.Mutex
+	mails []model.Mail
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mm *MemoryMailer) Send(ctx context.Context, mail model.Mail) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.mails = append(mm.mails, mail)
+	return nil
+}
+
+// Mails returns the mails sent so far, oldest first
+func (mm *MemoryMailer) Mails() []model.Mail {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return append([]model.Mail(nil), mm.mails...)
+}
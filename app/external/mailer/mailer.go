@@ -0,0 +1,77 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/config"
+)
+
+const (
+	DriverSMTP   = "smtp"
+	DriverFile   = "file"
+	DriverMemory = "memory"
+)
+
+type IMailer interface {
+	Send(ctx context.Context, mail model.Mail) error
+}
+
+// NewMailer returns the mailer selected by mail.driver
+func NewMailer(cfg *config.Config) (IMailer, error) {
+	switch cfg.MailConfig.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverFile:
+		return NewFileMailer(cfg.MailConfig.FilePath), nil
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.MailConfig.Driver)
+}
+
+// SMTPMailer sends plain text mails through an SMTP server, authenticating when a username is configured
+type SMTPMailer struct {
+	Cfg *config.Config
+}
+
+func NewSMTPMailer(cfg *config.Config) *SMTPMailer {
+	return &SMTPMailer{
+		Cfg: cfg,
+	}
+}
+
+// Send delivers mail. From may have a display name ("Kenalan <no-reply@kenalan.local>"), it is kept in the From
+// header while the envelope sender is only its address.
+func (sm *SMTPMailer) Send(ctx context.Context, mail model.Mail) error {
+	mailConfig := sm.Cfg.MailConfig
+	if strings.ContainsAny(mail.To, "\r\n") || strings.ContainsAny(mail.Subject, "\r\n") {
+		return errors.New("mail headers must not contain line breaks")
+	}
+	from, err := netmail.ParseAddress(mailConfig.From)
+	if err != nil {
+		return fmt.Errorf("parse mail from %q: %w", mailConfig.From, err)
+	}
+
+	var auth smtp.Auth
+	if mailConfig.Username != "" {
+		auth = smtp.PlainAuth("", mailConfig.Username, mailConfig.Password, mailConfig.Host)
+	}
+
+	message := "From: " + mailConfig.From + "\r\n" +
+		"To: " + mail.To + "\r\n" +
+		"Subject: " + mail.Subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + mail.Body
+
+	address := net.JoinHostPort(mailConfig.Host, strconv.Itoa(mailConfig.Port))
+	return smtp.SendMail(address, auth, from.Address, []string{mail.To}, []byte(message))
+}
@@ -0,0 +1,104 @@
+package payment_gateway
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/atrariksa/kenalan-core/app/model"
+)
+
+// FakePaymentGateway is a local stand-in of the payment gateway. Creating a payment intent returns a payment URL
+// on the fake itself, opening it settles the payment and sends a signed callback to WebhookURL.
+// Append ?status=failed to the payment URL to simulate a failed payment.
+type FakePaymentGateway struct {
+	BaseURL       string
+	WebhookURL    string
+	WebhookSecret string
+
+	mu      sync.Mutex
+	intents map[string]CreatePaymentIntentRequest
+}
+
+func NewFakePaymentGateway(baseURL string, webhookURL string, webhookSecret string) *FakePaymentGateway {
+	return &FakePaymentGateway{
+		BaseURL:       baseURL,
+		WebhookURL:    webhookURL,
+		WebhookSecret: webhookSecret,
+		intents:       make(map[string]CreatePaymentIntentRequest),
+	}
+}
+
+func (fg *FakePaymentGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/payment_intents":
+		fg.createPaymentIntent(w, r)
+	case strings.HasPrefix(r.URL.Path, "/pay/"):
+		fg.pay(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (fg *FakePaymentGateway) createPaymentIntent(w http.ResponseWriter, r *http.Request) {
+	var req CreatePaymentIntentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OrderID == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+	id := "pi_" + hex.EncodeToString(idBytes)
+
+	fg.mu.Lock()
+	fg.intents[id] = req
+	fg.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.PaymentIntent{
+		ID:         id,
+		PaymentURL: fmt.Sprintf("%s/pay/%s", fg.BaseURL, id),
+	})
+}
+
+func (fg *FakePaymentGateway) pay(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/pay/")
+	fg.mu.Lock()
+	req, ok := fg.intents[id]
+	fg.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	status := "paid"
+	if r.URL.Query().Get("status") == "failed" {
+		status = "failed"
+	}
+
+	payload, _ := json.Marshal(model.PaymentNotification{
+		OrderID:   req.OrderID,
+		PaymentID: id,
+		Status:    status,
+	})
+	callback, _ := http.NewRequest(http.MethodPost, fg.WebhookURL, bytes.NewReader(payload))
+	callback.Header.Set("Content-Type", "application/json")
+	callback.Header.Set(SignatureHeader, Sign(fg.WebhookSecret, payload))
+
+	resp, err := http.DefaultClient.Do(callback)
+	if err != nil {
+		log.Printf("send webhook for %s failed: %v", req.OrderID, err)
+		http.Error(w, "webhook failed", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	fmt.Fprintf(w, "order %s %s, webhook responded %d\n", req.OrderID, status, resp.StatusCode)
+}
@@ -0,0 +1,110 @@
+package payment_gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/config"
+)
+
+// SignatureHeader carries the hex encoded HMAC-SHA256 of the raw webhook body
+const SignatureHeader = "X-Signature"
+
+type IPaymentGateway interface {
+	CreatePaymentIntent(ctx context.Context, order model.Order) (model.PaymentIntent, error)
+	ParseNotification(payload []byte, signature string) (model.PaymentNotification, error)
+}
+
+type CreatePaymentIntentRequest struct {
+	OrderID     string `json:"order_id"`
+	Amount      int64  `json:"amount"`
+	Currency    string `json:"currency"`
+	Description string `json:"description"`
+}
+
+// HTTPPaymentGateway talks to the payment gateway REST API
+type HTTPPaymentGateway struct {
+	Cfg    *config.Config
+	Client *http.Client
+}
+
+func NewHTTPPaymentGateway(cfg *config.Config) *HTTPPaymentGateway {
+	return &HTTPPaymentGateway{
+		Cfg:    cfg,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (pg *HTTPPaymentGateway) CreatePaymentIntent(ctx context.Context, order model.Order) (model.PaymentIntent, error) {
+	var paymentIntent model.PaymentIntent
+	body, _ := json.Marshal(CreatePaymentIntentRequest{
+		OrderID:     order.ID,
+		Amount:      order.Amount,
+		Currency:    order.Currency,
+		Description: order.ProductCode,
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pg.Cfg.PaymentConfig.GatewayURL+"/payment_intents", bytes.NewReader(body))
+	if err != nil {
+		return paymentIntent, apperror.ErrInternal
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pg.Cfg.PaymentConfig.APIKey)
+
+	resp, err := pg.Client.Do(req)
+	if err != nil {
+		log.Printf("call CreatePaymentIntent failed: %v", err)
+		return paymentIntent, apperror.ErrInternal
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		log.Printf("call CreatePaymentIntent failed: status %d", resp.StatusCode)
+		return paymentIntent, apperror.ErrInternal
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&paymentIntent)
+	if err != nil {
+		return paymentIntent, apperror.ErrInternal
+	}
+	return paymentIntent, nil
+}
+
+// ParseNotification verifies the signature of a webhook payload before decoding it
+func (pg *HTTPPaymentGateway) ParseNotification(payload []byte, signature string) (model.PaymentNotification, error) {
+	var notification model.PaymentNotification
+	if !VerifySignature(pg.Cfg.PaymentConfig.WebhookSecret, payload, signature) {
+		return notification, apperror.ErrInvalidSignature
+	}
+
+	err := json.Unmarshal(payload, &notification)
+	if err != nil {
+		return notification, apperror.ErrValidation.WithMessage("payload is not valid")
+	}
+	return notification, nil
+}
+
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifySignature(secret string, payload []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
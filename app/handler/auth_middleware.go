@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/service"
+	"github.com/labstack/echo/v4"
+)
+
+// TokenExtractor reads the access token from the request, returning an empty string when there is none
+type TokenExtractor func(c echo.Context) string
+
+// TokenFromHeader reads a bearer token from the Authorization header
+func TokenFromHeader(c echo.Context) string {
+	authorization := c.Request().Header.Get(echo.HeaderAuthorization)
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+}
+
+// AuthMiddleware validates the access token once per request and stores the resolved principal in the
+// request context, handlers behind it read the caller with PrincipalFromContext. Extractors are tried in
+// order, TokenFromHeader is used when none is given. Requests without a valid token get 401.
+func AuthMiddleware(svc service.ICoreService, extractors ...TokenExtractor) echo.MiddlewareFunc {
+	if len(extractors) == 0 {
+		extractors = []TokenExtractor{TokenFromHeader}
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var token string
+			for _, extractor := range extractors {
+				token = extractor(c)
+				if token != "" {
+					break
+				}
+			}
+			if token == "" {
+				return apperror.ErrUnauthorized
+			}
+
+			principal, err := svc.Authenticate(c.Request().Context(), token)
+			if err != nil {
+				return err
+			}
+
+			ctx := model.ContextWithPrincipal(c.Request().Context(), principal)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// WSAuthMiddleware authenticates websocket upgrades by the bearer token, or by a single-use ticket from
+// POST /ws/ticket in the ticket query param for browsers, which can't set headers on websockets. Access tokens
+// are never read from the URL where they would end up in access logs.
+func WSAuthMiddleware(svc service.ICoreService) echo.MiddlewareFunc {
+	authenticateToken := AuthMiddleware(svc)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withToken := authenticateToken(next)
+		return func(c echo.Context) error {
+			if TokenFromHeader(c) != "" {
+				return withToken(c)
+			}
+
+			ticket := c.QueryParam("ticket")
+			if ticket == "" {
+				return apperror.ErrUnauthorized
+			}
+			principal, err := svc.AuthenticateWSTicket(c.Request().Context(), ticket)
+			if err != nil {
+				return err
+			}
+
+			ctx := model.ContextWithPrincipal(c.Request().Context(), principal)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// PrincipalFromContext returns the caller resolved by AuthMiddleware, it is only set on protected routes
+func PrincipalFromContext(c echo.Context) model.Principal {
+	principal, _ := model.PrincipalFromContext(c.Request().Context())
+	return principal
+}
@@ -1,52 +1,78 @@
 package handler
 
 import (
-	"context"
-	"errors"
+	"io"
 	"net/http"
-	"strings"
 
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/external/payment_gateway"
 	"github.com/atrariksa/kenalan-core/app/model"
 	"github.com/atrariksa/kenalan-core/app/service"
-	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/atrariksa/kenalan-core/config"
 	"github.com/labstack/echo/v4"
 )
 
 // CoreHandler  represent the httphandler for core
 type CoreHandler struct {
 	CoreService service.ICoreService
+	WSConfig    config.WebsocketConfig
 }
 
 // RegisterCoreHandler will initialize the cores/ resources endpoint
-func RegisterCoreHandler(e *echo.Echo, svc service.ICoreService) {
+func RegisterCoreHandler(e *echo.Echo, svc service.ICoreService, wsConfig config.WebsocketConfig, idempotent echo.MiddlewareFunc) {
 	handler := &CoreHandler{
 		CoreService: svc,
+		WSConfig:    wsConfig,
 	}
-	e.POST("v1/kenalan/sign_up", handler.SignUp)
-	e.POST("v1/kenalan/login", handler.Login)
-	e.POST("v1/kenalan/view_profile", handler.ViewProfile)
-	e.POST("v1/kenalan/purchase", handler.Purchase)
+
+	public := e.Group("v1/kenalan")
+	public.POST("/sign_up", handler.SignUp)
+	public.POST("/login", handler.Login)
+	public.POST("/token/refresh", handler.RefreshToken)
+	public.POST("/password/forgot", handler.ForgotPassword)
+	public.POST("/password/reset", handler.ResetPassword)
+	public.POST("/email/verify", handler.VerifyEmail)
+	public.POST("/email/verify/resend", handler.ResendVerificationEmail)
+	public.GET("/products", handler.GetProducts)
+	public.POST("/payments/webhook", handler.PaymentWebhook)
+	public.GET("/ws", handler.Events, WSAuthMiddleware(svc))
+
+	protected := e.Group("v1/kenalan", AuthMiddleware(svc))
+	protected.POST("/logout", handler.Logout)
+	protected.POST("/logout_all", handler.LogoutAll)
+	protected.POST("/ws/ticket", handler.IssueWSTicket)
+	protected.POST("/view_profile", handler.ViewProfile)
+	protected.GET("/quota", handler.GetQuota)
+	protected.POST("/purchase", handler.Purchase, idempotent)
+	protected.POST("/gifts", handler.Gift, idempotent)
+	protected.GET("/matches", handler.GetMatches)
+	protected.DELETE("/matches/:id", handler.Unmatch)
+	protected.POST("/matches/:id/messages", handler.SendMessage)
+	protected.GET("/matches/:id/messages", handler.GetMessages)
+	protected.POST("/matches/:id/messages/read", handler.MarkMessagesRead)
+	protected.GET("/likes", handler.GetLikes)
+	protected.POST("/likes/:id/like_back", handler.LikeBack)
 }
 
 func (ch *CoreHandler) SignUp(c echo.Context) (err error) {
 	var signUpRequest model.SignUpRequest
 	err = c.Bind(&signUpRequest)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	err = signUpRequest.Validate()
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
-	err = ch.CoreService.SignUp(context.Background(), signUpRequest)
+	err = ch.CoreService.SignUp(c.Request().Context(), signUpRequest)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	return c.JSON(http.StatusCreated, model.SignUpResponse{
-		Code:    "0000",
+		Code:    model.CodeSuccess,
 		Message: "Success",
 	})
 }
@@ -55,22 +81,179 @@ func (ch *CoreHandler) Login(c echo.Context) (err error) {
 	var loginRequest model.LoginRequest
 	err = c.Bind(&loginRequest)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	err = loginRequest.Validate()
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
-	token, err := ch.CoreService.Login(context.Background(), loginRequest)
+	loginRequest.IP = c.RealIP()
+
+	tokenPair, err := ch.CoreService.Login(c.Request().Context(), loginRequest)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	return c.JSON(http.StatusOK, model.LoginResponse{
-		Code:  "0000",
-		Token: token,
+		Code:         model.CodeSuccess,
+		Token:        tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+	})
+}
+
+func (ch *CoreHandler) RefreshToken(c echo.Context) (err error) {
+	var refreshTokenRequest model.RefreshTokenRequest
+	err = c.Bind(&refreshTokenRequest)
+	if err != nil {
+		return err
+	}
+
+	err = refreshTokenRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	tokenPair, err := ch.CoreService.RefreshToken(c.Request().Context(), refreshTokenRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.RefreshTokenResponse{
+		Code:         model.CodeSuccess,
+		Token:        tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+	})
+}
+
+func (ch *CoreHandler) ForgotPassword(c echo.Context) (err error) {
+	var forgotPasswordRequest model.ForgotPasswordRequest
+	err = c.Bind(&forgotPasswordRequest)
+	if err != nil {
+		return err
+	}
+
+	err = forgotPasswordRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = ch.CoreService.ForgotPassword(c.Request().Context(), forgotPasswordRequest)
+	if err != nil {
+		return err
+	}
+
+	// same answer whether the account exists or not
+	return c.JSON(http.StatusOK, model.ForgotPasswordResponse{
+		Code:    model.CodeSuccess,
+		Message: "If the account exists, a reset link has been sent",
+	})
+}
+
+func (ch *CoreHandler) ResetPassword(c echo.Context) (err error) {
+	var resetPasswordRequest model.ResetPasswordRequest
+	err = c.Bind(&resetPasswordRequest)
+	if err != nil {
+		return err
+	}
+
+	err = resetPasswordRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = ch.CoreService.ResetPassword(c.Request().Context(), resetPasswordRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.ResetPasswordResponse{
+		Code:    model.CodeSuccess,
+		Message: "Success",
+	})
+}
+
+func (ch *CoreHandler) VerifyEmail(c echo.Context) (err error) {
+	var verifyEmailRequest model.VerifyEmailRequest
+	err = c.Bind(&verifyEmailRequest)
+	if err != nil {
+		return err
+	}
+
+	err = verifyEmailRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = ch.CoreService.VerifyEmail(c.Request().Context(), verifyEmailRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.VerifyEmailResponse{
+		Code:    model.CodeSuccess,
+		Message: "Success",
+	})
+}
+
+func (ch *CoreHandler) ResendVerificationEmail(c echo.Context) (err error) {
+	var resendVerificationEmailRequest model.ResendVerificationEmailRequest
+	err = c.Bind(&resendVerificationEmailRequest)
+	if err != nil {
+		return err
+	}
+
+	err = resendVerificationEmailRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = ch.CoreService.ResendVerificationEmail(c.Request().Context(), resendVerificationEmailRequest)
+	if err != nil {
+		return err
+	}
+
+	// same answer whether the account exists or not
+	return c.JSON(http.StatusOK, model.ResendVerificationEmailResponse{
+		Code:    model.CodeSuccess,
+		Message: "If the account is waiting for verification, a verification link has been sent",
+	})
+}
+
+// Logout ends the session of the token sent with the request
+func (ch *CoreHandler) Logout(c echo.Context) (err error) {
+	return ch.logout(c, false)
+}
+
+// LogoutAll ends every session of the caller, including the one of the token sent with the request
+func (ch *CoreHandler) LogoutAll(c echo.Context) (err error) {
+	return ch.logout(c, true)
+}
+
+func (ch *CoreHandler) logout(c echo.Context, allDevices bool) (err error) {
+	err = ch.CoreService.Logout(c.Request().Context(), PrincipalFromContext(c), allDevices)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.LogoutResponse{
+		Code:    model.CodeSuccess,
+		Message: "Success",
+	})
+}
+
+// IssueWSTicket returns a single-use ticket for opening /ws from clients that can't send the access token in a header
+func (ch *CoreHandler) IssueWSTicket(c echo.Context) (err error) {
+	ticket, err := ch.CoreService.IssueWSTicket(c.Request().Context(), PrincipalFromContext(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.WSTicketResponse{
+		Code:      model.CodeSuccess,
+		Ticket:    ticket,
+		ExpiresIn: int64(ch.WSConfig.TicketTTL.Seconds()),
 	})
 }
 
@@ -78,32 +261,253 @@ func (ch *CoreHandler) ViewProfile(c echo.Context) (err error) {
 	var viewProfileRequest model.ViewProfileRequest
 	err = c.Bind(&viewProfileRequest)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	err = viewProfileRequest.Validate()
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
-	token := c.Request().Header.Get("Authorization")
-	token = strings.Replace(token, "Bearer ", "", -1)
-	viewProfileRequest.Token = token
+	viewProfileRequest.Principal = PrincipalFromContext(c)
 
-	profile, err := ch.CoreService.ViewProfile(context.Background(), viewProfileRequest)
+	result, err := ch.CoreService.ViewProfile(c.Request().Context(), viewProfileRequest)
 	if err != nil {
-		if err.Error() == util.ErrUnauthorized {
-			return c.JSON(http.StatusUnauthorized, err.Error())
-		}
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	return c.JSON(http.StatusOK, model.ViewProfileResponse{
-		Code:       "0000",
-		ID:         profile.ID,
-		Fullname:   profile.Fullname,
-		IsVerified: profile.IsVerified,
-		PhotoURL:   profile.PhotoURL,
+		Code:       model.CodeSuccess,
+		ID:         result.NextProfile.ID,
+		Fullname:   result.NextProfile.Fullname,
+		IsVerified: result.NextProfile.IsVerified,
+		PhotoURL:   result.NextProfile.PhotoURL,
+		IsMatch:    result.Match != nil,
+		Match:      result.Match,
+	})
+}
+
+func (ch *CoreHandler) GetQuota(c echo.Context) (err error) {
+	var quotaRequest model.QuotaRequest
+	quotaRequest.Principal = PrincipalFromContext(c)
+
+	quotaStatus, err := ch.CoreService.GetQuota(c.Request().Context(), quotaRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.QuotaResponse{
+		Code:        model.CodeSuccess,
+		IsUnlimited: quotaStatus.IsUnlimited,
+		Limit:       quotaStatus.Limit,
+		Remaining:   quotaStatus.Remaining,
+		ResetAt:     quotaStatus.ResetAt,
+	})
+}
+
+func (ch *CoreHandler) GetMatches(c echo.Context) (err error) {
+	var matchesRequest model.MatchesRequest
+	err = c.Bind(&matchesRequest)
+	if err != nil {
+		return err
+	}
+
+	err = matchesRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	matchesRequest.Principal = PrincipalFromContext(c)
+
+	matches, total, err := ch.CoreService.GetMatches(c.Request().Context(), matchesRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.MatchesResponse{
+		Code:     model.CodeSuccess,
+		Matches:  matches,
+		Page:     matchesRequest.Page,
+		PageSize: matchesRequest.PageSize,
+		Total:    total,
+	})
+}
+
+func (ch *CoreHandler) Unmatch(c echo.Context) (err error) {
+	var unmatchRequest model.UnmatchRequest
+	err = c.Bind(&unmatchRequest)
+	if err != nil {
+		return err
+	}
+
+	err = unmatchRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	unmatchRequest.Principal = PrincipalFromContext(c)
+
+	err = ch.CoreService.Unmatch(c.Request().Context(), unmatchRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.UnmatchResponse{
+		Code:    model.CodeSuccess,
+		Message: "Success",
+	})
+}
+
+func (ch *CoreHandler) SendMessage(c echo.Context) (err error) {
+	var sendMessageRequest model.SendMessageRequest
+	err = c.Bind(&sendMessageRequest)
+	if err != nil {
+		return err
+	}
+
+	err = sendMessageRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	sendMessageRequest.Principal = PrincipalFromContext(c)
+
+	message, err := ch.CoreService.SendMessage(c.Request().Context(), sendMessageRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, model.SendMessageResponse{
+		Code:    model.CodeSuccess,
+		Message: message,
+	})
+}
+
+func (ch *CoreHandler) GetMessages(c echo.Context) (err error) {
+	var messagesRequest model.MessagesRequest
+	err = c.Bind(&messagesRequest)
+	if err != nil {
+		return err
+	}
+
+	err = messagesRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	messagesRequest.Principal = PrincipalFromContext(c)
+
+	messages, nextCursor, err := ch.CoreService.GetMessages(c.Request().Context(), messagesRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.MessagesResponse{
+		Code:       model.CodeSuccess,
+		Messages:   messages,
+		NextCursor: nextCursor,
+	})
+}
+
+func (ch *CoreHandler) MarkMessagesRead(c echo.Context) (err error) {
+	var markMessagesReadRequest model.MarkMessagesReadRequest
+	err = c.Bind(&markMessagesReadRequest)
+	if err != nil {
+		return err
+	}
+
+	err = markMessagesReadRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	markMessagesReadRequest.Principal = PrincipalFromContext(c)
+
+	err = ch.CoreService.MarkMessagesRead(c.Request().Context(), markMessagesReadRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.MarkMessagesReadResponse{
+		Code:    model.CodeSuccess,
+		Message: "Success",
+	})
+}
+
+func (ch *CoreHandler) GetLikes(c echo.Context) (err error) {
+	var likesRequest model.LikesRequest
+	err = c.Bind(&likesRequest)
+	if err != nil {
+		return err
+	}
+
+	err = likesRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	likesRequest.Principal = PrincipalFromContext(c)
+
+	likes, total, err := ch.CoreService.GetLikes(c.Request().Context(), likesRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.LikesResponse{
+		Code:     model.CodeSuccess,
+		Likes:    likes,
+		Page:     likesRequest.Page,
+		PageSize: likesRequest.PageSize,
+		Total:    total,
+	})
+}
+
+func (ch *CoreHandler) LikeBack(c echo.Context) (err error) {
+	var likeBackRequest model.LikeBackRequest
+	err = c.Bind(&likeBackRequest)
+	if err != nil {
+		return err
+	}
+
+	err = likeBackRequest.Validate()
+	if err != nil {
+		return err
+	}
+
+	likeBackRequest.Principal = PrincipalFromContext(c)
+
+	match, err := ch.CoreService.LikeBack(c.Request().Context(), likeBackRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.LikeBackResponse{
+		Code:  model.CodeSuccess,
+		Match: match,
+	})
+}
+
+func (ch *CoreHandler) GetProducts(c echo.Context) (err error) {
+	products, err := ch.CoreService.GetProducts(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	productResponses := make([]model.ProductResponse, 0, len(products))
+	for _, product := range products {
+		productResponses = append(productResponses, model.ProductResponse{
+			Code:            product.Code,
+			Name:            product.Name,
+			Price:           product.Price,
+			Currency:        product.Currency,
+			DurationSeconds: int64(product.Duration.Seconds()),
+			Entitlements:    product.Entitlements,
+		})
+	}
+
+	return c.JSON(http.StatusOK, model.ProductsResponse{
+		Code:     model.CodeSuccess,
+		Products: productResponses,
 	})
 }
 
@@ -111,31 +515,72 @@ func (ch *CoreHandler) Purchase(c echo.Context) (err error) {
 	var purchaseRequest model.PurchaseRequest
 	err = c.Bind(&purchaseRequest)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	err = purchaseRequest.Validate()
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return err
+	}
+
+	purchaseRequest.Principal = PrincipalFromContext(c)
+
+	order, err := ch.CoreService.Purchase(c.Request().Context(), purchaseRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.PurchaseResponse{
+		Code:       model.CodeSuccess,
+		Message:    "Success",
+		OrderID:    order.ID,
+		Status:     order.Status,
+		PaymentURL: order.PaymentURL,
+	})
+}
+
+func (ch *CoreHandler) Gift(c echo.Context) (err error) {
+	var giftRequest model.GiftRequest
+	err = c.Bind(&giftRequest)
+	if err != nil {
+		return err
 	}
 
-	token := c.Request().Header.Get("Authorization")
-	token = strings.Replace(token, "Bearer ", "", -1)
-	if token == "" {
-		return c.JSON(http.StatusUnauthorized, errors.New(util.ErrUnauthorized))
+	err = giftRequest.Validate()
+	if err != nil {
+		return err
 	}
-	purchaseRequest.Token = token
 
-	err = ch.CoreService.Purchase(context.Background(), purchaseRequest)
+	giftRequest.Principal = PrincipalFromContext(c)
+
+	order, err := ch.CoreService.Gift(c.Request().Context(), giftRequest)
 	if err != nil {
-		if err.Error() == util.ErrProductNotFound {
-			return c.JSON(http.StatusBadRequest, err.Error())
-		}
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return c.JSON(http.StatusOK, model.PurchaseResponse{
-		Code:    "0000",
+		Code:       model.CodeSuccess,
+		Message:    "Success",
+		OrderID:    order.ID,
+		Status:     order.Status,
+		PaymentURL: order.PaymentURL,
+	})
+}
+
+func (ch *CoreHandler) PaymentWebhook(c echo.Context) (err error) {
+	payload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return apperror.ErrValidation.Wrap(err)
+	}
+
+	signature := c.Request().Header.Get(payment_gateway.SignatureHeader)
+	err = ch.CoreService.HandlePaymentNotification(c.Request().Context(), payload, signature)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, model.PaymentWebhookResponse{
+		Code:    model.CodeSuccess,
 		Message: "Success",
 	})
 }
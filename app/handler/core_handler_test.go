@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/repository"
+	"github.com/atrariksa/kenalan-core/app/service"
+	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/atrariksa/kenalan-core/config"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+
+	pb "github.com/atrariksa/kenalan-core/app/external/grpc_client"
+)
+
+// fakeUserClient serves the viewer and an endless supply of candidates, and counts granted subscriptions
+type fakeUserClient struct {
+	pb.UserServiceClient
+	nextID  int64
+	upserts atomic.Int64
+}
+
+func (f *fakeUserClient) GetUserSubscription(ctx context.Context, in *pb.GetUserSubscriptionRequest, opts ...grpc.CallOption) (*pb.GetUserSubscriptionResponse, error) {
+	return &pb.GetUserSubscriptionResponse{User: &pb.User{Id: 1, Email: in.Email, Gender: "M"}}, nil
+}
+
+func (f *fakeUserClient) GetNextProfileExceptIDs(ctx context.Context, in *pb.GetNextProfileExceptIDsRequest, opts ...grpc.CallOption) (*pb.GetNextProfileExceptIDsResponse, error) {
+	f.nextID++
+	return &pb.GetNextProfileExceptIDsResponse{User: &pb.User{Id: 100 + f.nextID, Gender: in.Gender}}, nil
+}
+
+func (f *fakeUserClient) UpsertSubscription(ctx context.Context, in *pb.UpsertSubscriptionRequest, opts ...grpc.CallOption) (*pb.UpsertSubscriptionResponse, error) {
+	f.upserts.Add(1)
+	return &pb.UpsertSubscriptionResponse{Message: "success"}, nil
+}
+
+func TestViewProfileIgnoresTimezoneHeader(t *testing.T) {
+	// 11:59 in Jakarta, the default timezone, and a minute before midnight in Bogota
+	now := time.Date(2024, 1, 1, 4, 59, 0, 0, time.UTC)
+	util.TimeNow = func() time.Time { return now }
+	t.Cleanup(func() { util.TimeNow = time.Now })
+
+	mr := miniredis.RunT(t)
+	mr.SetTime(now)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+
+	cfg := &config.Config{
+		QuotaConfig:   config.QuotaConfig{DailySwipeLimit: 2, DefaultTimezone: "Asia/Jakarta"},
+		HistoryConfig: config.HistoryConfig{Retention: time.Hour, MaxExcludeIDs: 100},
+	}
+	svc := service.NewCoreService(nil, repository.NewRedisCoreRepository(rc), nil, repository.NewRedisEventRepository(rc),
+		repository.NewRedisJobRepository(rc), repository.NewConfigProductRepository(cfg), nil, nil, nil, nil, nil, nil,
+		&fakeUserClient{}, nil, nil, nil, nil, cfg)
+	ch := &CoreHandler{CoreService: svc}
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+
+	swipeLeft := func(timezone string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/kenalan/view_profile", strings.NewReader(`{"swipe_left": true}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set("X-Timezone", timezone)
+		principal := model.Principal{UserID: 1, Email: "viewer@kenalan.local", Gender: "M"}
+		req = req.WithContext(model.ContextWithPrincipal(req.Context(), principal))
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		err := ch.ViewProfile(c)
+		if err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := swipeLeft("America/Bogota"); code != http.StatusOK {
+			t.Fatalf("swipe %d answered %d", i+1, code)
+		}
+	}
+	if code := swipeLeft("America/Bogota"); code != http.StatusTooManyRequests {
+		t.Fatalf("swipe over the limit answered %d", code)
+	}
+
+	// midnight has passed in Bogota but not in the timezone of the user
+	now = now.Add(2 * time.Minute)
+	mr.SetTime(now)
+	mr.FastForward(2 * time.Minute)
+	for _, timezone := range []string{"America/Bogota", "Pacific/Kiritimati", ""} {
+		if code := swipeLeft(timezone); code != http.StatusTooManyRequests {
+			t.Fatalf("swipe with X-Timezone %q answered %d, want the quota still exhausted", timezone, code)
+		}
+	}
+}
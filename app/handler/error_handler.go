@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/labstack/echo/v4"
+)
+
+// HTTPErrorHandler renders every error returned by handlers and middleware as model.ErrorResponse with the
+// status of its apperror.Error. Errors raised by echo keep their status, any other error is logged and
+// answered as an internal error without its details.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	appErr := toAppError(err)
+	if appErr.Status >= http.StatusInternalServerError {
+		log.Printf("%s %s failed: %v", c.Request().Method, c.Request().URL.Path, err)
+	}
+
+	if appErr.Status == http.StatusUnauthorized {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	}
+	if appErr.RetryAfter > 0 {
+		c.Response().Header().Set(echo.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(appErr.Status)
+	} else {
+		err = c.JSON(appErr.Status, model.ErrorResponse{
+			Code:    appErr.Code,
+			Message: appErr.Message,
+		})
+	}
+	if err != nil {
+		log.Printf("write error response failed: %v", err)
+	}
+}
+
+func toAppError(err error) *apperror.Error {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		message, _ := httpErr.Message.(string)
+		return apperror.FromHTTPStatus(httpErr.Code, message).Wrap(err)
+	}
+	return apperror.As(err)
+}
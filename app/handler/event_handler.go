@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/net/websocket"
+)
+
+// Events upgrades the connection to a websocket pushing match, message and typing events to the client.
+// A ping event is sent every ping-interval, the client answers with a pong event and the connection is closed
+// once nothing was received within pong-wait.
+func (ch *CoreHandler) Events(c echo.Context) (err error) {
+	if !ch.originAllowed(c.Request()) {
+		return apperror.ErrForbidden.WithMessage("origin not allowed")
+	}
+
+	ctx := c.Request().Context()
+	subscription, err := ch.CoreService.SubscribeEvents(ctx, PrincipalFromContext(c))
+	if err != nil {
+		return err
+	}
+	defer subscription.Close()
+
+	server := websocket.Server{
+		// Origin is checked by originAllowed before subscribing
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+
+			done := make(chan struct{})
+			defer close(done)
+			go ch.sendEvents(ws, subscription.Events, done)
+
+			for {
+				ws.SetReadDeadline(time.Now().Add(ch.WSConfig.PongWait))
+				var clientEvent model.ClientEvent
+				if err := websocket.JSON.Receive(ws, &clientEvent); err != nil {
+					return
+				}
+
+				if clientEvent.Type == util.EventTypeTyping {
+					if err := ch.CoreService.SendTyping(ctx, subscription.UserID, clientEvent.ToUserID); err != nil {
+						log.Printf("send typing from %d failed: %v", subscription.UserID, err)
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// sendEvents writes events and periodic pings to ws until done is closed or a write fails
+func (ch *CoreHandler) sendEvents(ws *websocket.Conn, events <-chan model.Event, done <-chan struct{}) {
+	ticker := time.NewTicker(ch.WSConfig.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		var event model.Event
+		select {
+		case <-done:
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			event = e
+		case <-ticker.C:
+			event = model.Event{Type: util.EventTypePing}
+		}
+
+		ws.SetWriteDeadline(time.Now().Add(ch.WSConfig.WriteWait))
+		if err := websocket.JSON.Send(ws, event); err != nil {
+			ws.Close()
+			return
+		}
+	}
+}
+
+// originAllowed accepts requests without Origin, sent by mobile clients, and browsers on an allowed origin
+func (ch *CoreHandler) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get(echo.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range ch.WSConfig.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/repository"
+	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/labstack/echo/v4"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyMiddleware makes a mutating route safe to retry. The first request carrying an Idempotency-Key
+// runs the handler and its response is kept for ttl, retries with the same key and body get that response
+// replayed. A retry arriving while the first request is still running gets 409, reusing the key with a
+// different body gets 422. The running request holds the key for lockTTL only, so the key is freed soon when
+// the request never completes. The lock carries a random owner token and a request only completes or releases
+// a key it still holds, never one taken over by a retry after its lock expired. Requests without the header are
+// passed through as is.
+func IdempotencyMiddleware(repo repository.IIdempotencyRepository, lockTTL time.Duration, ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			idempotencyKey := c.Request().Header.Get(IdempotencyKeyHeader)
+			if idempotencyKey == "" {
+				return next(c)
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return apperror.ErrValidation.Wrap(err)
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := c.Request().Context()
+			// keys are scoped per caller and route so clients can't collide with each other
+			caller := c.Request().Header.Get(echo.HeaderAuthorization)
+			if principal, ok := model.PrincipalFromContext(ctx); ok {
+				caller = strconv.FormatInt(principal.UserID, 10)
+			}
+			key := hashHex(caller, c.Request().Method, c.Path(), idempotencyKey)
+			fingerprint := hashHex(string(body))
+
+			owner := util.RandomID(16)
+			record, isAcquired, err := repo.AcquireIdempotencyKey(ctx, key, model.IdempotencyRecord{
+				Status:      util.IdempotencyStatusProcessing,
+				Owner:       owner,
+				Fingerprint: fingerprint,
+			}, lockTTL)
+			if err != nil {
+				return err
+			}
+
+			if !isAcquired {
+				if record.Fingerprint != fingerprint {
+					return apperror.ErrIdempotencyKeyReused
+				}
+				if record.Status == util.IdempotencyStatusProcessing {
+					return apperror.ErrIdempotencyKeyInUse
+				}
+				c.Response().Header().Set("Idempotent-Replayed", "true")
+				return c.Blob(record.StatusCode, record.ContentType, record.Body)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			err = next(c)
+			if err != nil {
+				c.Error(err)
+			}
+			// the outcome is kept even when the request ran out of time
+			ctx = context.WithoutCancel(ctx)
+
+			// server errors are not kept so the client can retry them
+			if c.Response().Status >= http.StatusInternalServerError {
+				isReleased, releaseErr := repo.ReleaseIdempotencyKey(ctx, key, owner)
+				if releaseErr != nil {
+					log.Printf("release idempotency key failed: %v", releaseErr)
+				} else if !isReleased {
+					log.Printf("release idempotency key skipped: lock expired before the request completed")
+				}
+				return nil
+			}
+
+			isCompleted, err := repo.CompleteIdempotencyKey(ctx, key, owner, model.IdempotencyRecord{
+				Status:      util.IdempotencyStatusCompleted,
+				Fingerprint: fingerprint,
+				StatusCode:  c.Response().Status,
+				ContentType: c.Response().Header().Get(echo.HeaderContentType),
+				Body:        recorder.body.Bytes(),
+			}, ttl)
+			if err != nil {
+				log.Printf("complete idempotency key failed: %v", err)
+			} else if !isCompleted {
+				log.Printf("complete idempotency key skipped: lock expired before the request completed")
+			}
+			return nil
+		}
+	}
+}
+
+// responseRecorder writes through to the client while keeping a copy of the body
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.body.Write(b)
+	return rr.ResponseWriter.Write(b)
+}
+
+func hashHex(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/atrariksa/kenalan-core/app/external/payment_gateway"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/repository"
+	"github.com/atrariksa/kenalan-core/app/service"
+	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/atrariksa/kenalan-core/config"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+// paymentTest runs the webhook route of core and the fake payment gateway calling it over HTTP
+type paymentTest struct {
+	cs         *service.CoreService
+	userClient *fakeUserClient
+	webhookURL string
+}
+
+func newPaymentTest(t *testing.T) *paymentTest {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	core := httptest.NewServer(e)
+	t.Cleanup(core.Close)
+	webhookURL := core.URL + "/v1/kenalan/payments/webhook"
+
+	fake := payment_gateway.NewFakePaymentGateway("", webhookURL, testWebhookSecret)
+	gateway := httptest.NewServer(fake)
+	t.Cleanup(gateway.Close)
+	fake.BaseURL = gateway.URL
+
+	cfg := &config.Config{
+		QuotaConfig:   config.QuotaConfig{DailySwipeLimit: 10, DefaultTimezone: "Asia/Jakarta"},
+		HistoryConfig: config.HistoryConfig{Retention: time.Hour, MaxExcludeIDs: 100},
+		PaymentConfig: config.PaymentConfig{GatewayURL: gateway.URL, WebhookSecret: testWebhookSecret, ProcessingTimeout: time.Minute},
+		Products: []config.ProductConfig{
+			{Code: "SKU001", Name: "Unlimited Swipe", Price: 50000, Currency: "IDR", Duration: 30 * 24 * time.Hour, Entitlements: []string{util.EntitlementUnlimitedSwipe}},
+		},
+	}
+	userClient := &fakeUserClient{}
+	cs := service.NewCoreService(nil, repository.NewRedisCoreRepository(rc), nil, repository.NewRedisEventRepository(rc),
+		repository.NewRedisJobRepository(rc), repository.NewConfigProductRepository(cfg), repository.NewRedisOrderRepository(rc),
+		repository.NewRedisAuditRepository(rc), nil, nil, nil, nil, userClient, nil, payment_gateway.NewHTTPPaymentGateway(cfg),
+		nil, nil, cfg)
+	ch := &CoreHandler{CoreService: cs}
+	e.POST("/v1/kenalan/payments/webhook", ch.PaymentWebhook)
+
+	return &paymentTest{cs: cs, userClient: userClient, webhookURL: webhookURL}
+}
+
+// purchase creates an order at the fake gateway for a test user
+func (pt *paymentTest) purchase(t *testing.T) model.Order {
+	t.Helper()
+	order, err := pt.cs.Purchase(context.Background(), model.PurchaseRequest{
+		Principal:   model.Principal{UserID: 1, Email: "buyer@kenalan.local", Gender: "M"},
+		ProductCode: "SKU001",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+// notify posts a notification of order to the webhook with signature and returns the status code
+func (pt *paymentTest) notify(t *testing.T, order model.Order, signature func([]byte) string) int {
+	t.Helper()
+	payload, _ := json.Marshal(model.PaymentNotification{OrderID: order.ID, PaymentID: order.PaymentID, Status: util.OrderStatusPaid})
+	req, err := http.NewRequest(http.MethodPost, pt.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if sig := signature(payload); sig != "" {
+		req.Header.Set(payment_gateway.SignatureHeader, sig)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (pt *paymentTest) orderStatus(t *testing.T, id string) string {
+	t.Helper()
+	order, err := pt.cs.OrderRepo.GetOrder(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order.Status
+}
+
+func TestPaymentWebhookSignature(t *testing.T) {
+	tests := []struct {
+		name       string
+		signature  func([]byte) string
+		wantCode   int
+		wantStatus string
+	}{
+		{
+			name:       "valid signature",
+			signature:  func(payload []byte) string { return payment_gateway.Sign(testWebhookSecret, payload) },
+			wantCode:   http.StatusOK,
+			wantStatus: util.OrderStatusPaid,
+		},
+		{
+			name:       "signed with another secret",
+			signature:  func(payload []byte) string { return payment_gateway.Sign("other-secret", payload) },
+			wantCode:   http.StatusUnauthorized,
+			wantStatus: util.OrderStatusPending,
+		},
+		{
+			name:       "signature of another payload",
+			signature:  func([]byte) string { return payment_gateway.Sign(testWebhookSecret, []byte(`{}`)) },
+			wantCode:   http.StatusUnauthorized,
+			wantStatus: util.OrderStatusPending,
+		},
+		{
+			name:       "signature not hex",
+			signature:  func([]byte) string { return "not-a-signature" },
+			wantCode:   http.StatusUnauthorized,
+			wantStatus: util.OrderStatusPending,
+		},
+		{
+			name:       "missing signature",
+			signature:  func([]byte) string { return "" },
+			wantCode:   http.StatusUnauthorized,
+			wantStatus: util.OrderStatusPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := newPaymentTest(t)
+			order := pt.purchase(t)
+
+			if code := pt.notify(t, order, tt.signature); code != tt.wantCode {
+				t.Fatalf("webhook answered %d, want %d", code, tt.wantCode)
+			}
+			if status := pt.orderStatus(t, order.ID); status != tt.wantStatus {
+				t.Fatalf("order %s, want %s", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestPaymentWebhookDuplicateFulfilledOnce(t *testing.T) {
+	pt := newPaymentTest(t)
+	order := pt.purchase(t)
+
+	// settle at the fake gateway, which calls the webhook
+	resp, err := http.Get(order.PaymentURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if status := pt.orderStatus(t, order.ID); status != util.OrderStatusPaid {
+		t.Fatalf("order %s after payment, want paid", status)
+	}
+
+	// the gateway delivers the same notification again
+	sign := func(payload []byte) string { return payment_gateway.Sign(testWebhookSecret, payload) }
+	for i := 0; i < 2; i++ {
+		if code := pt.notify(t, order, sign); code != http.StatusOK {
+			t.Fatalf("duplicate notification answered %d, want 200", code)
+		}
+	}
+	if upserts := pt.userClient.upserts.Load(); upserts != 1 {
+		t.Fatalf("subscription granted %d times, want once", upserts)
+	}
+}
+
+func TestStaleProcessingOrderRecovered(t *testing.T) {
+	pt := newPaymentTest(t)
+	ctx := context.Background()
+	now := util.TimeNow()
+
+	// core stopped while fulfilling one order long ago and another one just now
+	stale := pt.purchase(t)
+	_, err := pt.cs.OrderRepo.TransitionOrderStatus(ctx, stale.ID, util.OrderStatusPending, util.OrderStatusProcessing, now.Add(-2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recent := pt.purchase(t)
+	_, err = pt.cs.OrderRepo.TransitionOrderStatus(ctx, recent.ID, util.OrderStatusPending, util.OrderStatusProcessing, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = pt.cs.HandleDelayedJob(ctx, model.DelayedJob{Type: util.JobTypeRecoverOrder, Email: recent.Email, OrderID: recent.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := pt.orderStatus(t, recent.ID); status != util.OrderStatusProcessing {
+		t.Fatalf("order still within processing-timeout %s, want left processing", status)
+	}
+	if upserts := pt.userClient.upserts.Load(); upserts != 0 {
+		t.Fatalf("subscription granted %d times, want none yet", upserts)
+	}
+
+	for i := 0; i < 2; i++ {
+		err = pt.cs.HandleDelayedJob(ctx, model.DelayedJob{Type: util.JobTypeRecoverOrder, Email: stale.Email, OrderID: stale.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if status := pt.orderStatus(t, stale.ID); status != util.OrderStatusPaid {
+		t.Fatalf("stale order %s, want recovered to paid", status)
+	}
+	if upserts := pt.userClient.upserts.Load(); upserts != 1 {
+		t.Fatalf("subscription granted %d times, want once", upserts)
+	}
+}
@@ -1,12 +1,22 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/atrariksa/kenalan-core/app/external/grpc_client"
+	"github.com/atrariksa/kenalan-core/app/external/jwt_verifier"
+	"github.com/atrariksa/kenalan-core/app/external/mailer"
+	"github.com/atrariksa/kenalan-core/app/external/payment_gateway"
 	"github.com/atrariksa/kenalan-core/app/repository"
 	"github.com/atrariksa/kenalan-core/app/service"
 	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/atrariksa/kenalan-core/app/worker"
 	"github.com/atrariksa/kenalan-core/config"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -15,6 +25,7 @@ import (
 func SetupServer() {
 	// Echo instance
 	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
 
 	// Middleware
 	e.Use(middleware.Logger())
@@ -24,13 +35,85 @@ func SetupServer() {
 	e.GET("/health", health)
 
 	cfg := config.GetConfig()
+	e.IPExtractor = echo.ExtractIPDirect()
+	if cfg.ServerConfig.BehindProxy {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	}
+	// one deadline for the whole request, downstream calls get what is left of it
+	if cfg.ServerConfig.RequestTimeout > 0 {
+		e.Use(middleware.ContextTimeoutWithConfig(middleware.ContextTimeoutConfig{
+			Skipper: func(c echo.Context) bool {
+				return strings.EqualFold(c.Request().Header.Get(echo.HeaderUpgrade), "websocket")
+			},
+			Timeout: cfg.ServerConfig.RequestTimeout,
+		}))
+	}
+
 	coreRepo := repository.NewCoreRepository()
-	redisRepo := repository.NewRedisCoreRepository(util.GetRedisClient(cfg))
-	svc := service.NewCoreService(coreRepo, redisRepo, cfg)
-	RegisterCoreHandler(e, svc)
+	redisClient := util.GetRedisClient(cfg)
+	redisRepo := repository.NewRedisCoreRepository(redisClient)
+	messageRepo := repository.NewRedisMessageRepository(redisClient)
+	eventRepo := repository.NewRedisEventRepository(redisClient)
+	jobRepo := repository.NewRedisJobRepository(redisClient)
+	productRepo := repository.NewConfigProductRepository(cfg)
+	orderRepo := repository.NewRedisOrderRepository(redisClient)
+	paymentGateway := payment_gateway.NewHTTPPaymentGateway(cfg)
+	auditRepo := repository.NewRedisAuditRepository(redisClient)
+	tokenRepo := repository.NewRedisTokenRepository(redisClient)
+	loginAttemptRepo := repository.NewRedisLoginAttemptRepository(redisClient)
+	passwordResetRepo := repository.NewRedisPasswordResetRepository(redisClient)
+	emailVerificationRepo := repository.NewRedisEmailVerificationRepository(redisClient)
+	mailSender, err := mailer.NewMailer(cfg)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	var jwtVerifier jwt_verifier.IJWTVerifier
+	keySetVerifier, err := jwt_verifier.NewKeySetVerifier(cfg.AuthConfig.JWT)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	if keySetVerifier != nil {
+		jwtVerifier = keySetVerifier
+	}
+	userConn, err := grpc_client.NewConnection("user service", cfg.UserServerConfig)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	defer userConn.Close()
+	authConn, err := grpc_client.NewConnection("auth service", cfg.AuthServerConfig)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	defer authConn.Close()
+	userClient := grpc_client.NewUserServiceClient(userConn)
+	authClient := grpc_client.NewAuthServiceClient(authConn)
+	svc := service.NewCoreService(coreRepo, redisRepo, messageRepo, eventRepo, jobRepo, productRepo, orderRepo, auditRepo, tokenRepo, loginAttemptRepo, passwordResetRepo, emailVerificationRepo, userClient, authClient, paymentGateway, jwtVerifier, mailSender, cfg)
+	idempotencyRepo := repository.NewRedisIdempotencyRepository(redisClient)
+	RegisterCoreHandler(e, svc, cfg.WebsocketConfig, IdempotencyMiddleware(idempotencyRepo, cfg.IdempotencyConfig.LockTTL, cfg.IdempotencyConfig.TTL))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Workers
+	go worker.NewDelayedJobWorker(jobRepo, svc, cfg).Run(ctx)
 
 	// Start server
-	e.Logger.Fatal(e.Start(fmt.Sprintf("%v", cfg.ServerConfig.Host) + ":" + fmt.Sprintf("%v", cfg.ServerConfig.Port)))
+	go func() {
+		err := e.Start(fmt.Sprintf("%v", cfg.ServerConfig.Host) + ":" + fmt.Sprintf("%v", cfg.ServerConfig.Port))
+		if err != nil && err != http.ErrServerClosed {
+			e.Logger.Error(err)
+			stop()
+		}
+	}()
+
+	// the downstream connections are closed by the defers above once in-flight requests are done
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ServerConfig.ShutdownTimeout)
+	defer cancel()
+	err = e.Shutdown(shutdownCtx)
+	if err != nil {
+		e.Logger.Error(err)
+	}
 }
 
 func health(c echo.Context) error {
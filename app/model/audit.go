@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type AuditEntry struct {
+	Action      string    `json:"action"`
+	ActorID     int64     `json:"actor_id"`
+	ActorEmail  string    `json:"actor_email"`
+	TargetID    int64     `json:"target_id"`
+	TargetEmail string    `json:"target_email"`
+	OrderID     string    `json:"order_id"`
+	ProductCode string    `json:"product_code"`
+	At          time.Time `json:"at"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type DelayedJob struct {
+	Type        string    `json:"type"`
+	Email       string    `json:"email"`
+	ProductCode string    `json:"product_code"`
+	OrderID     string    `json:"order_id,omitempty"`
+	RunAt       time.Time `json:"run_at"`
+	Attempt     int       `json:"attempt"`
+}
@@ -0,0 +1,20 @@
+package model
+
+type Event struct {
+	Type       string   `json:"type"`
+	FromUserID int64    `json:"from_user_id,omitempty"`
+	Match      *Match   `json:"match,omitempty"`
+	Message    *Message `json:"message,omitempty"`
+}
+
+// ClientEvent is an event sent by the client over the websocket
+type ClientEvent struct {
+	Type     string `json:"type"`
+	ToUserID int64  `json:"to_user_id"`
+}
+
+type EventSubscription struct {
+	UserID int64
+	Events <-chan Event
+	Close  func() error
+}
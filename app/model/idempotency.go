@@ -0,0 +1,11 @@
+package model
+
+// IdempotencyRecord Owner is the random token of the request holding the key while it is processing
+type IdempotencyRecord struct {
+	Status      string `json:"status"`
+	Owner       string `json:"owner,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
@@ -0,0 +1,10 @@
+package model
+
+import "time"
+
+// Like is an entry of the "who liked me" inbox. Profile is left out when IsRedacted is true.
+type Like struct {
+	Profile    *Profile  `json:"profile,omitempty"`
+	LikedAt    time.Time `json:"liked_at"`
+	IsRedacted bool      `json:"is_redacted"`
+}
@@ -0,0 +1,8 @@
+package model
+
+import "time"
+
+type Match struct {
+	Profile
+	MatchedAt time.Time `json:"matched_at"`
+}
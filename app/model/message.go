@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type Message struct {
+	ID          int64     `json:"id"`
+	SenderID    int64     `json:"sender_id"`
+	RecipientID int64     `json:"recipient_id"`
+	Text        string    `json:"text"`
+	SentAt      time.Time `json:"sent_at"`
+	IsRead      bool      `json:"is_read"`
+}
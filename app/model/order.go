@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+type Order struct {
+	ID           string    `json:"id"`
+	UserID       int64     `json:"user_id"`
+	Email        string    `json:"email"`
+	GifterUserID int64     `json:"gifter_user_id,omitempty"`
+	GifterEmail  string    `json:"gifter_email,omitempty"`
+	ProductCode  string    `json:"product_code"`
+	Amount       int64     `json:"amount"`
+	Currency     string    `json:"currency"`
+	Status       string    `json:"status"`
+	PaymentID    string    `json:"payment_id"`
+	PaymentURL   string    `json:"payment_url"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// SubscriptionExpiredAt is the expiry granted by the order, recorded before granting it so fulfilling the
+	// order again sets the same expiry instead of extending it twice
+	SubscriptionExpiredAt time.Time `json:"subscription_expired_at"`
+}
+
+type PaymentIntent struct {
+	ID         string `json:"id"`
+	PaymentURL string `json:"payment_url"`
+}
+
+// PaymentNotification is the callback sent by the payment gateway once a payment settles
+type PaymentNotification struct {
+	OrderID   string `json:"order_id"`
+	PaymentID string `json:"payment_id"`
+	Status    string `json:"status"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "context"
+
+// Principal is the authenticated caller resolved from the access token, TokenHash and Token describe
+// that token so the session can be ended
+type Principal struct {
+	UserID int64
+	Email  string
+	Gender string
+
+	TokenHash string
+	Token     TokenInfo
+}
+
+type principalContextKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type Product struct {
+	Code         string
+	Name         string
+	Price        int64
+	Currency     string
+	Duration     time.Duration
+	Entitlements []string
+}
+
+func (p Product) HasEntitlement(entitlement string) bool {
+	for i := 0; i < len(p.Entitlements); i++ {
+		if p.Entitlements[i] == entitlement {
+			return true
+		}
+	}
+	return false
+}
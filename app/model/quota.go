@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type SwipeQuota struct {
+	SwipeCount int64     `json:"swipe_count"`
+	ResetAt    time.Time `json:"reset_at"`
+}
+
+type QuotaStatus struct {
+	IsUnlimited bool
+	Limit       int64
+	Remaining   int64
+	ResetAt     time.Time
+}
@@ -1,10 +1,12 @@
 package model
 
 import (
-	"errors"
 	"fmt"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/atrariksa/kenalan-core/app/apperror"
 	"github.com/atrariksa/kenalan-core/app/util"
 )
 
@@ -14,6 +16,7 @@ type SignUpRequest struct {
 	DOB      string `json:"dob"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Timezone string `json:"timezone"`
 }
 
 func (sur *SignUpRequest) Validate() error {
@@ -40,8 +43,13 @@ func (sur *SignUpRequest) Validate() error {
 	if sur.Password == "" {
 		errMessage += fmt.Sprintf(errTemplate, "password")
 	}
+	if sur.Timezone != "" {
+		if _, err := time.LoadLocation(sur.Timezone); err != nil {
+			errMessage += fmt.Sprintf(errTemplate, "timezone")
+		}
+	}
 	if errMessage != "" {
-		return errors.New(errMessage)
+		return apperror.ErrValidation.WithMessage(errMessage)
 	}
 	return nil
 }
@@ -49,6 +57,7 @@ func (sur *SignUpRequest) Validate() error {
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	IP       string `json:"-"`
 }
 
 func (lr *LoginRequest) Validate() error {
@@ -61,16 +70,100 @@ func (lr *LoginRequest) Validate() error {
 		errMessage += fmt.Sprintf(errTemplate, "password")
 	}
 	if errMessage != "" {
-		return errors.New(errMessage)
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (rtr *RefreshTokenRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if rtr.RefreshToken == "" {
+		errMessage += fmt.Sprintf(errTemplate, "refresh_token")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+func (fpr *ForgotPasswordRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if fpr.Email == "" {
+		errMessage += fmt.Sprintf(errTemplate, "email")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (rpr *ResetPasswordRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if rpr.Token == "" {
+		errMessage += fmt.Sprintf(errTemplate, "token")
+	}
+	if rpr.Password == "" {
+		errMessage += fmt.Sprintf(errTemplate, "password")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (ver *VerifyEmailRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if ver.Token == "" {
+		errMessage += fmt.Sprintf(errTemplate, "token")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type ResendVerificationEmailRequest struct {
+	Email string `json:"email"`
+}
+
+func (rver *ResendVerificationEmailRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if rver.Email == "" {
+		errMessage += fmt.Sprintf(errTemplate, "email")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
 	}
 	return nil
 }
 
 type ViewProfileRequest struct {
-	Token                  string
-	SwipeLeft              bool  `json:"swipe_left"`
-	SwipeRight             bool  `json:"swipe_right"`
-	CurrentViewedProfileID int64 `json:"current_viewed_profile_id"`
+	Principal              Principal `json:"-"`
+	SwipeLeft              bool      `json:"swipe_left"`
+	SwipeRight             bool      `json:"swipe_right"`
+	CurrentViewedProfileID int64     `json:"current_viewed_profile_id"`
 }
 
 func (vpr *ViewProfileRequest) Validate() error {
@@ -89,46 +182,218 @@ func (vpr *ViewProfileRequest) Validate() error {
 	}
 
 	if errMessage != "" {
-		return errors.New(errMessage)
+		return apperror.ErrValidation.WithMessage(errMessage)
 	}
 
 	return nil
 }
 
+// PurchaseRequest ProductName and ExpiredAt are derived from the product catalog, not taken from the client.
+// UserID is optional and must match the caller when sent.
 type PurchaseRequest struct {
-	Token       string
-	UserID      int64  `json:"user_id"`
-	ProductCode string `json:"product_code"`
-	ProductName string `json:"product_name"`
-	ExpiredAt   string `json:"expired_at"`
+	Principal   Principal `json:"-"`
+	UserID      int64     `json:"user_id"`
+	ProductCode string    `json:"product_code"`
+	ProductName string    `json:"-"`
+	ExpiredAt   string    `json:"-"`
 }
 
 func (pr *PurchaseRequest) Validate() error {
 	var errMessage string
 	errTemplate := "%s is not valid;"
-	if pr.UserID < 1 {
+	if pr.UserID < 0 {
 		errMessage += fmt.Sprintf(errTemplate, "user_id")
 	}
 	if pr.ProductCode == "" {
 		errMessage += fmt.Sprintf(errTemplate, "product_code")
 	}
-	if pr.ProductCode != util.UnlimitedSwipeProductCode &&
-		pr.ProductCode != util.AccountVerifiedProductCode {
-		errMessage += fmt.Sprintf(errTemplate, "product_code")
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+
+	return nil
+}
+
+type MatchesRequest struct {
+	Principal Principal `json:"-"`
+	Page      int64     `query:"page"`
+	PageSize  int64     `query:"page_size"`
+}
+
+func (mr *MatchesRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if mr.Page == 0 {
+		mr.Page = 1
+	}
+	if mr.PageSize == 0 {
+		mr.PageSize = util.DefaultPageSize
+	}
+	if mr.Page < 1 {
+		errMessage += fmt.Sprintf(errTemplate, "page")
+	}
+	if mr.PageSize < 1 || mr.PageSize > util.MaxPageSize {
+		errMessage += fmt.Sprintf(errTemplate, "page_size")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type UnmatchRequest struct {
+	Principal     Principal `json:"-"`
+	MatchedUserID int64     `param:"id"`
+}
+
+func (ur *UnmatchRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if ur.MatchedUserID < 1 {
+		errMessage += fmt.Sprintf(errTemplate, "id")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type SendMessageRequest struct {
+	Principal     Principal `json:"-"`
+	MatchedUserID int64     `param:"id"`
+	Text          string    `json:"text"`
+}
+
+func (smr *SendMessageRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if smr.MatchedUserID < 1 {
+		errMessage += fmt.Sprintf(errTemplate, "id")
+	}
+	smr.Text = util.SanitizeText(smr.Text)
+	if smr.Text == "" || utf8.RuneCountInString(smr.Text) > util.MaxMessageLength {
+		errMessage += fmt.Sprintf(errTemplate, "text")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type MessagesRequest struct {
+	Principal     Principal `json:"-"`
+	MatchedUserID int64     `param:"id"`
+	Cursor        int64     `query:"cursor"`
+	Limit         int64     `query:"limit"`
+}
+
+func (mr *MessagesRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if mr.Limit == 0 {
+		mr.Limit = util.DefaultPageSize
+	}
+	if mr.MatchedUserID < 1 {
+		errMessage += fmt.Sprintf(errTemplate, "id")
+	}
+	if mr.Cursor < 0 {
+		errMessage += fmt.Sprintf(errTemplate, "cursor")
+	}
+	if mr.Limit < 1 || mr.Limit > util.MaxPageSize {
+		errMessage += fmt.Sprintf(errTemplate, "limit")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type MarkMessagesReadRequest struct {
+	Principal     Principal `json:"-"`
+	MatchedUserID int64     `param:"id"`
+	MessageID     int64     `json:"message_id"`
+}
+
+func (mmr *MarkMessagesReadRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if mmr.MatchedUserID < 1 {
+		errMessage += fmt.Sprintf(errTemplate, "id")
 	}
-	if pr.ProductName == "" {
-		errMessage += fmt.Sprintf(errTemplate, "product_name")
+	if mmr.MessageID < 0 {
+		errMessage += fmt.Sprintf(errTemplate, "message_id")
 	}
-	if pr.ExpiredAt == "" {
-		errMessage += fmt.Sprintf(errTemplate, "expired_at")
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
 	}
-	if _, err := util.ToDateTimeYYYYMMDDTHHmmss(pr.ExpiredAt); err != nil {
-		fmt.Println(err)
-		errMessage += fmt.Sprintf(errTemplate, "expired_at")
+	return nil
+}
+
+type LikesRequest struct {
+	Principal Principal `json:"-"`
+	Page      int64     `query:"page"`
+	PageSize  int64     `query:"page_size"`
+}
+
+func (lr *LikesRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if lr.Page == 0 {
+		lr.Page = 1
+	}
+	if lr.PageSize == 0 {
+		lr.PageSize = util.DefaultPageSize
+	}
+	if lr.Page < 1 {
+		errMessage += fmt.Sprintf(errTemplate, "page")
+	}
+	if lr.PageSize < 1 || lr.PageSize > util.MaxPageSize {
+		errMessage += fmt.Sprintf(errTemplate, "page_size")
 	}
 	if errMessage != "" {
-		return errors.New(errMessage)
+		return apperror.ErrValidation.WithMessage(errMessage)
 	}
+	return nil
+}
+
+type LikeBackRequest struct {
+	Principal   Principal `json:"-"`
+	LikerUserID int64     `param:"id"`
+}
 
+func (lbr *LikeBackRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if lbr.LikerUserID < 1 {
+		errMessage += fmt.Sprintf(errTemplate, "id")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
+	return nil
+}
+
+type QuotaRequest struct {
+	Principal Principal `json:"-"`
+}
+
+type GiftRequest struct {
+	Principal      Principal `json:"-"`
+	RecipientEmail string    `json:"recipient_email"`
+	ProductCode    string    `json:"product_code"`
+}
+
+func (gr *GiftRequest) Validate() error {
+	var errMessage string
+	errTemplate := "%s is not valid;"
+	if gr.RecipientEmail == "" {
+		errMessage += fmt.Sprintf(errTemplate, "recipient_email")
+	}
+	if gr.ProductCode == "" {
+		errMessage += fmt.Sprintf(errTemplate, "product_code")
+	}
+	if errMessage != "" {
+		return apperror.ErrValidation.WithMessage(errMessage)
+	}
 	return nil
 }
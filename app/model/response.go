@@ -1,13 +1,56 @@
 package model
 
+import "time"
+
+// CodeSuccess is the code of every successful response, failures carry the code of their apperror.Error
+const CodeSuccess = "0000"
+
 type SignUpResponse struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 }
 
 type LoginResponse struct {
-	Code  string `json:"code"`
-	Token string `json:"token"`
+	Code         string `json:"code"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RefreshTokenResponse struct {
+	Code         string `json:"code"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type WSTicketResponse struct {
+	Code      string `json:"code"`
+	Ticket    string `json:"ticket"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+type LogoutResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ForgotPasswordResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ResetPasswordResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type VerifyEmailResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type ResendVerificationEmailResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type ViewProfileResponse struct {
@@ -16,9 +59,89 @@ type ViewProfileResponse struct {
 	IsVerified bool   `json:"is_verified"`
 	Fullname   string `json:"full_name"`
 	PhotoURL   string `json:"photo_url"`
+	IsMatch    bool   `json:"is_match"`
+	Match      *Match `json:"match,omitempty"`
 }
 
 type PurchaseResponse struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	OrderID    string `json:"order_id"`
+	Status     string `json:"status"`
+	PaymentURL string `json:"payment_url"`
+}
+
+type PaymentWebhookResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type MatchesResponse struct {
+	Code     string  `json:"code"`
+	Matches  []Match `json:"matches"`
+	Page     int64   `json:"page"`
+	PageSize int64   `json:"page_size"`
+	Total    int64   `json:"total"`
+}
+
+type UnmatchResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type SendMessageResponse struct {
+	Code    string  `json:"code"`
+	Message Message `json:"message"`
+}
+
+type MessagesResponse struct {
+	Code       string    `json:"code"`
+	Messages   []Message `json:"messages"`
+	NextCursor int64     `json:"next_cursor"`
+}
+
+type MarkMessagesReadResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type LikesResponse struct {
+	Code     string `json:"code"`
+	Likes    []Like `json:"likes"`
+	Page     int64  `json:"page"`
+	PageSize int64  `json:"page_size"`
+	Total    int64  `json:"total"`
+}
+
+type LikeBackResponse struct {
+	Code  string `json:"code"`
+	Match *Match `json:"match"`
+}
+
+type QuotaResponse struct {
+	Code        string    `json:"code"`
+	IsUnlimited bool      `json:"is_unlimited"`
+	Limit       int64     `json:"limit"`
+	Remaining   int64     `json:"remaining"`
+	ResetAt     time.Time `json:"reset_at"`
+}
+
+type ProductResponse struct {
+	Code            string   `json:"code"`
+	Name            string   `json:"name"`
+	Price           int64    `json:"price"`
+	Currency        string   `json:"currency"`
+	DurationSeconds int64    `json:"duration_seconds"`
+	Entitlements    []string `json:"entitlements"`
+}
+
+type ProductsResponse struct {
+	Code     string            `json:"code"`
+	Products []ProductResponse `json:"products"`
+}
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 }
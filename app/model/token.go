@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// TokenInfo is what core knows about a validated access token. IssuedAt of opaque tokens is when core handed
+// them out, or first saw them when they were issued elsewhere, their ExpiresAt is unknown and left zero.
+type TokenInfo struct {
+	Email     string    `json:"email"`
+	TokenID   string    `json:"token_id"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TokenRevocation RevokedBefore is when the user last logged out of all devices, tokens issued until then are revoked
+type TokenRevocation struct {
+	IsRevoked     bool
+	RevokedBefore time.Time
+}
+
+// Revokes tells whether a token issued at issuedAt is revoked. Both sides are compared in milliseconds, the
+// precision revocations are stored with. JWT iat only has whole seconds, a token issued in the second of the
+// revocation is taken to be issued after it so logging in right after logging out of all devices works.
+func (tr TokenRevocation) Revokes(issuedAt time.Time) bool {
+	if tr.IsRevoked {
+		return true
+	}
+
+	precision := time.Millisecond
+	if issuedAt.Nanosecond() == 0 {
+		precision = time.Second
+	}
+	return issuedAt.Truncate(precision).Before(tr.RevokedBefore.Truncate(precision))
+}
+
+// TokenPair is what a client gets when it logs in or refreshes its session
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// RefreshTokenFamily is the chain of refresh tokens rotated from a single login. Only RefreshTokenHash, the
+// latest of the chain, may be used, presenting an older one revokes the family.
+type RefreshTokenFamily struct {
+	ID               string
+	Email            string
+	RefreshTokenHash string
+	AccessTokenHash  string
+	IsRevoked        bool
+	CreatedAt        time.Time
+}
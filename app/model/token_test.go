@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenRevocationRevokes(t *testing.T) {
+	revokedBefore := time.Date(2024, 1, 1, 12, 0, 0, 500*int(time.Millisecond), time.UTC)
+	revocation := TokenRevocation{RevokedBefore: revokedBefore}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     bool
+	}{
+		{name: "jwt issued a second earlier", issuedAt: revokedBefore.Truncate(time.Second).Add(-time.Second), want: true},
+		{name: "jwt issued in the same second", issuedAt: revokedBefore.Truncate(time.Second), want: false},
+		{name: "jwt issued a second later", issuedAt: revokedBefore.Truncate(time.Second).Add(time.Second), want: false},
+		{name: "opaque token issued earlier in the same second", issuedAt: revokedBefore.Add(-100 * time.Millisecond), want: true},
+		{name: "opaque token issued later in the same second", issuedAt: revokedBefore.Add(100 * time.Millisecond), want: false},
+		{name: "opaque token issued in the same millisecond", issuedAt: revokedBefore.Add(300 * time.Microsecond), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := revocation.Revokes(tt.issuedAt); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !(TokenRevocation{IsRevoked: true}).Revokes(revokedBefore.Add(time.Hour)) {
+		t.Fatal("revoked token not revoked")
+	}
+	if (TokenRevocation{}).Revokes(revokedBefore) {
+		t.Fatal("token revoked without any revocation")
+	}
+}
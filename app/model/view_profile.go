@@ -1,10 +1,24 @@
 package model
 
+import "time"
+
+// ViewProfile is the cached viewing session. EntitlementsExpiredAt is when the first subscription granting its
+// entitlements expires, zero when none was granted.
 type ViewProfile struct {
-	ViewerID         int64   `json:"viewer_id"`
-	ViewerGender     string  `json:"viewer_gender"`
-	Email            string  `json:"email"`
-	IsUnlimitedSwipe bool    `json:"is_unlimited_swipe"`
-	ViewedProfileIDs []int64 `json:"viewed_profile_ids"`
-	SwipeCount       int64   `json:"swipe_count"`
+	ViewerID              int64     `json:"viewer_id"`
+	ViewerGender          string    `json:"viewer_gender"`
+	Email                 string    `json:"email"`
+	IsUnlimitedSwipe      bool      `json:"is_unlimited_swipe"`
+	CanSeeLikes           bool      `json:"can_see_likes"`
+	EntitlementsExpiredAt time.Time `json:"entitlements_expired_at"`
+}
+
+// IsEntitlementExpired reports whether an entitlement of the session may have lapsed at now
+func (vp ViewProfile) IsEntitlementExpired(now time.Time) bool {
+	return !vp.EntitlementsExpiredAt.IsZero() && !now.Before(vp.EntitlementsExpiredAt)
+}
+
+type ViewProfileResult struct {
+	NextProfile Profile
+	Match       *Match
 }
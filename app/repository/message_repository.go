@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyMessageID = "message_id"
+var KeyConversation = "conversation:%d:%d"
+var KeyConversationRead = "conversation_read:%d:%d"
+
+// markReadScript only moves the read marker forward
+var markReadScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]) or '0')
+if tonumber(ARGV[2]) > current then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+return 1
+`)
+
+type IMessageRepository interface {
+	StoreMessage(ctx context.Context, message model.Message) (model.Message, error)
+	GetMessages(ctx context.Context, userID int64, otherUserID int64, beforeID int64, limit int64) ([]model.Message, error)
+	MarkRead(ctx context.Context, userID int64, otherUserID int64, upToID int64) error
+	GetLastReadID(ctx context.Context, userID int64, otherUserID int64) (int64, error)
+}
+
+type RedisMessageRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisMessageRepository(rc *redis.Client) *RedisMessageRepository {
+	return &RedisMessageRepository{
+		RC: rc,
+	}
+}
+
+// conversationKey formats key so both users of a pair resolve to the same conversation
+func conversationKey(format string, userID int64, otherUserID int64) string {
+	if userID > otherUserID {
+		userID, otherUserID = otherUserID, userID
+	}
+	return fmt.Sprintf(format, userID, otherUserID)
+}
+
+func (mr *RedisMessageRepository) StoreMessage(ctx context.Context, message model.Message) (model.Message, error) {
+	id, err := mr.RC.Incr(ctx, KeyMessageID).Result()
+	if err != nil {
+		return message, apperror.ErrInternal
+	}
+	message.ID = id
+
+	jsonData, _ := json.Marshal(message)
+	err = mr.RC.ZAdd(ctx, conversationKey(KeyConversation, message.SenderID, message.RecipientID), redis.Z{
+		Score:  float64(id),
+		Member: jsonData,
+	}).Err()
+	if err != nil {
+		return message, apperror.ErrInternal
+	}
+	return message, nil
+}
+
+// GetMessages returns up to limit messages older than beforeID, newest first. A zero beforeID starts from the latest message.
+func (mr *RedisMessageRepository) GetMessages(ctx context.Context, userID int64, otherUserID int64, beforeID int64, limit int64) ([]model.Message, error) {
+	max := "+inf"
+	if beforeID > 0 {
+		max = "(" + strconv.FormatInt(beforeID, 10)
+	}
+
+	members, err := mr.RC.ZRevRangeByScore(ctx, conversationKey(KeyConversation, userID, otherUserID), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   max,
+		Count: limit,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return nil, apperror.ErrInternal
+	}
+
+	messages := make([]model.Message, 0, len(members))
+	for i := 0; i < len(members); i++ {
+		var message model.Message
+		json.Unmarshal([]byte(members[i]), &message)
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// MarkRead marks messages up to upToID as read by userID
+func (mr *RedisMessageRepository) MarkRead(ctx context.Context, userID int64, otherUserID int64, upToID int64) error {
+	err := markReadScript.Run(ctx, mr.RC, []string{conversationKey(KeyConversationRead, userID, otherUserID)}, userID, upToID).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// GetLastReadID returns the latest message ID userID has read in the conversation
+func (mr *RedisMessageRepository) GetLastReadID(ctx context.Context, userID int64, otherUserID int64) (int64, error) {
+	id, err := mr.RC.HGet(ctx, conversationKey(KeyConversationRead, userID, otherUserID), strconv.FormatInt(userID, 10)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, apperror.ErrInternal
+	}
+	return id, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/config"
+)
+
+type IProductRepository interface {
+	GetProducts(ctx context.Context) ([]model.Product, error)
+	GetProduct(ctx context.Context, code string) (model.Product, error)
+}
+
+// ConfigProductRepository serves the product catalog defined in config
+type ConfigProductRepository struct {
+	Products []model.Product
+}
+
+func NewConfigProductRepository(cfg *config.Config) *ConfigProductRepository {
+	products := make([]model.Product, 0, len(cfg.Products))
+	for _, p := range cfg.Products {
+		products = append(products, model.Product{
+			Code:         p.Code,
+			Name:         p.Name,
+			Price:        p.Price,
+			Currency:     p.Currency,
+			Duration:     p.Duration,
+			Entitlements: p.Entitlements,
+		})
+	}
+	return &ConfigProductRepository{
+		Products: products,
+	}
+}
+
+func (pr *ConfigProductRepository) GetProducts(ctx context.Context) ([]model.Product, error) {
+	return pr.Products, nil
+}
+
+func (pr *ConfigProductRepository) GetProduct(ctx context.Context, code string) (model.Product, error) {
+	for i := 0; i < len(pr.Products); i++ {
+		if pr.Products[i].Code == code {
+			return pr.Products[i], nil
+		}
+	}
+	return model.Product{}, apperror.ErrProductNotFound
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyAuditLog = "audit_log"
+
+type IAuditRepository interface {
+	StoreAuditEntry(ctx context.Context, entry model.AuditEntry) error
+}
+
+type RedisAuditRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisAuditRepository(rc *redis.Client) *RedisAuditRepository {
+	return &RedisAuditRepository{
+		RC: rc,
+	}
+}
+
+func (ar *RedisAuditRepository) StoreAuditEntry(ctx context.Context, entry model.AuditEntry) error {
+	jsonData, _ := json.Marshal(entry)
+	err := ar.RC.RPush(ctx, KeyAuditLog, jsonData).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
@@ -3,16 +3,72 @@ package repository
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/atrariksa/kenalan-core/app/apperror"
 	"github.com/atrariksa/kenalan-core/app/model"
 	"github.com/atrariksa/kenalan-core/app/util"
 	"github.com/redis/go-redis/v9"
 )
 
+var KeyProfile = "profile:%d"
+var KeyLikes = "likes:%d"
+var KeyLikedBy = "liked_by:%d"
+var KeyMatches = "matches:%d"
+var KeyUnmatched = "unmatched:%d"
+var KeyTimezone = "timezone:%s"
+
+const swipeQuotaExceeded = -1
+const swipeProfileNotViewed = -2
+
+// consumeSwipeScript KEYS: quota hash, viewed sorted set. ARGV: limit (0 unlimited), reset_at in unix ms, liked id (0 none)
+var consumeSwipeScript = redis.NewScript(`
+local count = tonumber(redis.call('HGET', KEYS[1], 'swipe_count') or '0')
+local limit = tonumber(ARGV[1])
+if limit > 0 and count >= limit then
+	return -1
+end
+if ARGV[3] ~= '0' and not redis.call('ZSCORE', KEYS[2], ARGV[3]) then
+	return -2
+end
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	redis.call('HSET', KEYS[1], 'reset_at', ARGV[2])
+	redis.call('PEXPIREAT', KEYS[1], ARGV[2])
+end
+return redis.call('HINCRBY', KEYS[1], 'swipe_count', 1)
+`)
+
+var releaseSwipeScript = redis.NewScript(`
+if tonumber(redis.call('HGET', KEYS[1], 'swipe_count') or '0') > 0 then
+	redis.call('HINCRBY', KEYS[1], 'swipe_count', -1)
+end
+return 1
+`)
+
 type IRedisCoreRepository interface {
 	StoreViewProfile(ctx context.Context, key string, data model.ViewProfile) error
 	GetViewProfile(ctx context.Context, key string) (model.ViewProfile, error)
+	GetSwipeQuota(ctx context.Context, key string) (model.SwipeQuota, error)
+	ConsumeSwipe(ctx context.Context, quotaKey string, viewedKey string, limit int64, resetAt time.Time, likedID int64) (int64, error)
+	ReleaseSwipe(ctx context.Context, quotaKey string) error
+	AddViewedProfileID(ctx context.Context, key string, id int64, viewedAt time.Time, retention time.Duration) error
+	GetViewedProfileIDs(ctx context.Context, key string, limit int64) ([]int64, error)
+	IsViewedProfile(ctx context.Context, key string, id int64) (bool, error)
+	StoreProfile(ctx context.Context, profile model.Profile) error
+	GetProfile(ctx context.Context, id int64) (model.Profile, error)
+	StoreLike(ctx context.Context, viewerID int64, targetID int64, likedAt time.Time) error
+	IsLiked(ctx context.Context, viewerID int64, targetID int64) (bool, error)
+	StoreMatch(ctx context.Context, userID int64, otherUserID int64, matchedAt time.Time) error
+	IsMatched(ctx context.Context, userID int64, otherUserID int64) (bool, error)
+	GetMatches(ctx context.Context, userID int64, offset int64, limit int64) ([]model.Match, int64, error)
+	DeleteMatch(ctx context.Context, userID int64, otherUserID int64) error
+	GetUnmatchedIDs(ctx context.Context, userID int64) ([]int64, error)
+	IsUnmatched(ctx context.Context, userID int64, otherUserID int64) (bool, error)
+	GetLikedBy(ctx context.Context, userID int64, offset int64, limit int64) ([]model.Like, int64, error)
+	StoreTimezone(ctx context.Context, email string, timezone string) error
+	GetTimezone(ctx context.Context, email string) (string, error)
 }
 
 type RedisCoreRepository struct {
@@ -33,10 +89,300 @@ func (ar *RedisCoreRepository) StoreViewProfile(ctx context.Context, key string,
 func (ar *RedisCoreRepository) GetViewProfile(ctx context.Context, key string) (model.ViewProfile, error) {
 	jsonData, err := ar.RC.Get(ctx, key).Result()
 	if err != nil && err != redis.Nil {
-		return model.ViewProfile{}, errors.New(util.ErrInternalError)
+		return model.ViewProfile{}, apperror.ErrInternal
 	}
 
 	var viewProfileData model.ViewProfile
 	json.Unmarshal([]byte(jsonData), &viewProfileData)
 	return viewProfileData, nil
 }
+
+func (ar *RedisCoreRepository) GetSwipeQuota(ctx context.Context, key string) (model.SwipeQuota, error) {
+	data, err := ar.RC.HGetAll(ctx, key).Result()
+	if err != nil && err != redis.Nil {
+		return model.SwipeQuota{}, apperror.ErrInternal
+	}
+
+	var swipeQuota model.SwipeQuota
+	if len(data) == 0 {
+		return swipeQuota, nil
+	}
+	swipeQuota.SwipeCount, _ = strconv.ParseInt(data["swipe_count"], 10, 64)
+	resetAt, _ := strconv.ParseInt(data["reset_at"], 10, 64)
+	swipeQuota.ResetAt = time.UnixMilli(resetAt)
+	return swipeQuota, nil
+}
+
+// ConsumeSwipe checks the quota and counts a swipe in one step, so concurrent swipes can't go over the limit.
+// A limit of 0 means unlimited. resetAt is used when a new quota window is opened, the window expires with it.
+// When likedID is not 0 it must be in the viewed set, otherwise nothing is counted.
+func (ar *RedisCoreRepository) ConsumeSwipe(ctx context.Context, quotaKey string, viewedKey string, limit int64, resetAt time.Time, likedID int64) (int64, error) {
+	count, err := consumeSwipeScript.Run(ctx, ar.RC, []string{quotaKey, viewedKey}, limit, resetAt.UnixMilli(), likedID).Int64()
+	if err != nil {
+		return 0, apperror.ErrInternal
+	}
+
+	switch count {
+	case swipeQuotaExceeded:
+		return 0, apperror.ErrSwipeQuotaExceeded
+	case swipeProfileNotViewed:
+		return 0, apperror.ErrProfileNotViewed
+	}
+	return count, nil
+}
+
+func (ar *RedisCoreRepository) ReleaseSwipe(ctx context.Context, quotaKey string) error {
+	err := releaseSwipeScript.Run(ctx, ar.RC, []string{quotaKey}).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// AddViewedProfileID records id in the viewed history and drops entries older than retention
+func (ar *RedisCoreRepository) AddViewedProfileID(ctx context.Context, key string, id int64, viewedAt time.Time, retention time.Duration) error {
+	_, err := ar.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(viewedAt.Unix()), Member: id})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(viewedAt.Add(-retention).Unix(), 10))
+		pipe.Expire(ctx, key, retention)
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// GetViewedProfileIDs returns up to limit ids of the latest viewed profiles, none when limit isn't positive
+func (ar *RedisCoreRepository) GetViewedProfileIDs(ctx context.Context, key string, limit int64) ([]int64, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	members, err := ar.RC.ZRevRange(ctx, key, 0, limit-1).Result()
+	if err != nil && err != redis.Nil {
+		return nil, apperror.ErrInternal
+	}
+
+	ids := make([]int64, 0, len(members))
+	for i := 0; i < len(members); i++ {
+		id, err := strconv.ParseInt(members[i], 10, 64)
+		if err != nil {
+			return nil, apperror.ErrInternal
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (ar *RedisCoreRepository) IsViewedProfile(ctx context.Context, key string, id int64) (bool, error) {
+	err := ar.RC.ZScore(ctx, key, strconv.FormatInt(id, 10)).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return true, nil
+}
+
+// StoreProfile keeps a snapshot of a served profile so it can be shown again later, e.g. on a match
+func (ar *RedisCoreRepository) StoreProfile(ctx context.Context, profile model.Profile) error {
+	jsonData, _ := json.Marshal(profile)
+	return ar.RC.Set(ctx, fmt.Sprintf(KeyProfile, profile.ID), jsonData, 0).Err()
+}
+
+func (ar *RedisCoreRepository) GetProfile(ctx context.Context, id int64) (model.Profile, error) {
+	jsonData, err := ar.RC.Get(ctx, fmt.Sprintf(KeyProfile, id)).Result()
+	if err != nil && err != redis.Nil {
+		return model.Profile{}, apperror.ErrInternal
+	}
+
+	profile := model.Profile{ID: id}
+	json.Unmarshal([]byte(jsonData), &profile)
+	return profile, nil
+}
+
+// StoreLike records that viewerID swiped right on targetID, indexed from both sides
+func (ar *RedisCoreRepository) StoreLike(ctx context.Context, viewerID int64, targetID int64, likedAt time.Time) error {
+	_, err := ar.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, fmt.Sprintf(KeyLikes, viewerID), redis.Z{Score: float64(likedAt.Unix()), Member: targetID})
+		pipe.ZAdd(ctx, fmt.Sprintf(KeyLikedBy, targetID), redis.Z{Score: float64(likedAt.Unix()), Member: viewerID})
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+func (ar *RedisCoreRepository) IsLiked(ctx context.Context, viewerID int64, targetID int64) (bool, error) {
+	err := ar.RC.ZScore(ctx, fmt.Sprintf(KeyLikes, viewerID), fmt.Sprint(targetID)).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return true, nil
+}
+
+// StoreMatch adds the pair to both users' match lists and takes them out of each other's "who liked me" inbox
+func (ar *RedisCoreRepository) StoreMatch(ctx context.Context, userID int64, otherUserID int64, matchedAt time.Time) error {
+	_, err := ar.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, fmt.Sprintf(KeyMatches, userID), redis.Z{Score: float64(matchedAt.Unix()), Member: otherUserID})
+		pipe.ZAdd(ctx, fmt.Sprintf(KeyMatches, otherUserID), redis.Z{Score: float64(matchedAt.Unix()), Member: userID})
+		pipe.ZRem(ctx, fmt.Sprintf(KeyLikedBy, userID), otherUserID)
+		pipe.ZRem(ctx, fmt.Sprintf(KeyLikedBy, otherUserID), userID)
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+func (ar *RedisCoreRepository) IsMatched(ctx context.Context, userID int64, otherUserID int64) (bool, error) {
+	err := ar.RC.ZScore(ctx, fmt.Sprintf(KeyMatches, userID), fmt.Sprint(otherUserID)).Err()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return true, nil
+}
+
+// GetMatches returns a page of userID's matches, newest first, along with the total number of matches.
+// Only ID and MatchedAt are filled in the returned matches.
+func (ar *RedisCoreRepository) GetMatches(ctx context.Context, userID int64, offset int64, limit int64) ([]model.Match, int64, error) {
+	key := fmt.Sprintf(KeyMatches, userID)
+	total, err := ar.RC.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, 0, apperror.ErrInternal
+	}
+
+	members, err := ar.RC.ZRevRangeWithScores(ctx, key, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, apperror.ErrInternal
+	}
+
+	matches := make([]model.Match, 0, len(members))
+	for i := 0; i < len(members); i++ {
+		id, err := strconv.ParseInt(fmt.Sprint(members[i].Member), 10, 64)
+		if err != nil {
+			return nil, 0, apperror.ErrInternal
+		}
+		matches = append(matches, model.Match{
+			Profile:   model.Profile{ID: id},
+			MatchedAt: time.Unix(int64(members[i].Score), 0),
+		})
+	}
+	return matches, total, nil
+}
+
+// DeleteMatch removes the pair from both users' match lists, drops their likes on each other
+// and remembers the pair so neither profile is served to the other again
+func (ar *RedisCoreRepository) DeleteMatch(ctx context.Context, userID int64, otherUserID int64) error {
+	_, err := ar.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, fmt.Sprintf(KeyMatches, userID), otherUserID)
+		pipe.ZRem(ctx, fmt.Sprintf(KeyMatches, otherUserID), userID)
+		pipe.ZRem(ctx, fmt.Sprintf(KeyLikes, userID), otherUserID)
+		pipe.ZRem(ctx, fmt.Sprintf(KeyLikes, otherUserID), userID)
+		pipe.ZRem(ctx, fmt.Sprintf(KeyLikedBy, userID), otherUserID)
+		pipe.ZRem(ctx, fmt.Sprintf(KeyLikedBy, otherUserID), userID)
+		pipe.SAdd(ctx, fmt.Sprintf(KeyUnmatched, userID), otherUserID)
+		pipe.SAdd(ctx, fmt.Sprintf(KeyUnmatched, otherUserID), userID)
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+func (ar *RedisCoreRepository) GetUnmatchedIDs(ctx context.Context, userID int64) ([]int64, error) {
+	members, err := ar.RC.SMembers(ctx, fmt.Sprintf(KeyUnmatched, userID)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, apperror.ErrInternal
+	}
+
+	ids := make([]int64, 0, len(members))
+	for i := 0; i < len(members); i++ {
+		id, err := strconv.ParseInt(members[i], 10, 64)
+		if err != nil {
+			return nil, apperror.ErrInternal
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (ar *RedisCoreRepository) IsUnmatched(ctx context.Context, userID int64, otherUserID int64) (bool, error) {
+	isUnmatched, err := ar.RC.SIsMember(ctx, fmt.Sprintf(KeyUnmatched, userID), otherUserID).Result()
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return isUnmatched, nil
+}
+
+// GetLikedBy returns a page of users who liked userID and are neither matched nor unmatched, newest first, along
+// with their total. Likes left by unmatched users are dropped first. Only Profile.ID and LikedAt are filled in the
+// returned likes.
+func (ar *RedisCoreRepository) GetLikedBy(ctx context.Context, userID int64, offset int64, limit int64) ([]model.Like, int64, error) {
+	key := fmt.Sprintf(KeyLikedBy, userID)
+	unmatchedIDs, err := ar.GetUnmatchedIDs(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(unmatchedIDs) > 0 {
+		members := make([]interface{}, 0, len(unmatchedIDs))
+		for i := 0; i < len(unmatchedIDs); i++ {
+			members = append(members, unmatchedIDs[i])
+		}
+		err = ar.RC.ZRem(ctx, key, members...).Err()
+		if err != nil {
+			return nil, 0, apperror.ErrInternal
+		}
+	}
+
+	total, err := ar.RC.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, 0, apperror.ErrInternal
+	}
+
+	members, err := ar.RC.ZRevRangeWithScores(ctx, key, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, apperror.ErrInternal
+	}
+
+	likes := make([]model.Like, 0, len(members))
+	for i := 0; i < len(members); i++ {
+		id, err := strconv.ParseInt(fmt.Sprint(members[i].Member), 10, 64)
+		if err != nil {
+			return nil, 0, apperror.ErrInternal
+		}
+		likes = append(likes, model.Like{
+			Profile: &model.Profile{ID: id},
+			LikedAt: time.Unix(int64(members[i].Score), 0),
+		})
+	}
+	return likes, total, nil
+}
+
+// StoreTimezone keeps the timezone email signed up with, it is set once and never replaced
+func (ar *RedisCoreRepository) StoreTimezone(ctx context.Context, email string, timezone string) error {
+	err := ar.RC.SetNX(ctx, fmt.Sprintf(KeyTimezone, email), timezone, 0).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// GetTimezone returns the timezone email signed up with, empty when none was given
+func (ar *RedisCoreRepository) GetTimezone(ctx context.Context, email string) (string, error) {
+	timezone, err := ar.RC.Get(ctx, fmt.Sprintf(KeyTimezone, email)).Result()
+	if err != nil && err != redis.Nil {
+		return "", apperror.ErrInternal
+	}
+	return timezone, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestCoreRepository(t *testing.T) *RedisCoreRepository {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+	return NewRedisCoreRepository(rc)
+}
+
+func TestConsumeSwipeConcurrentLimit(t *testing.T) {
+	repo := newTestCoreRepository(t)
+	ctx := context.Background()
+	const limit = 10
+	const swipes = 50
+	resetAt := time.Now().Add(time.Hour)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, swipes)
+	for i := 0; i < swipes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.ConsumeSwipe(ctx, "swipe_quota:a@b.c", "viewed:a@b.c", limit, resetAt, 0)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var consumed, exceeded int
+	for err := range errs {
+		switch {
+		case err == nil:
+			consumed++
+		case errors.Is(err, apperror.ErrSwipeQuotaExceeded):
+			exceeded++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if consumed != limit || exceeded != swipes-limit {
+		t.Fatalf("consumed %d and exceeded %d, want %d and %d", consumed, exceeded, limit, swipes-limit)
+	}
+
+	quota, err := repo.GetSwipeQuota(ctx, "swipe_quota:a@b.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota.SwipeCount != limit {
+		t.Fatalf("swipe count %d, want %d", quota.SwipeCount, limit)
+	}
+}
+
+func TestReleaseSwipeFreesQuota(t *testing.T) {
+	repo := newTestCoreRepository(t)
+	ctx := context.Background()
+	resetAt := time.Now().Add(time.Hour)
+
+	_, err := repo.ConsumeSwipe(ctx, "swipe_quota:a@b.c", "viewed:a@b.c", 1, resetAt, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.ConsumeSwipe(ctx, "swipe_quota:a@b.c", "viewed:a@b.c", 1, resetAt, 0)
+	if !errors.Is(err, apperror.ErrSwipeQuotaExceeded) {
+		t.Fatalf("got %v, want swipe quota exceeded", err)
+	}
+
+	err = repo.ReleaseSwipe(ctx, "swipe_quota:a@b.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.ConsumeSwipe(ctx, "swipe_quota:a@b.c", "viewed:a@b.c", 1, resetAt, 0)
+	if err != nil {
+		t.Fatalf("swipe after release: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyEmailVerification = "email_verification:%s"
+var KeyEmailVerificationByEmail = "email_verification_by_email:%s"
+var KeyEmailVerificationCooldown = "email_verification_cooldown:%s"
+
+// KeyEmailUnverified marks accounts that signed up but haven't verified their email yet. Accounts without
+// the marker, including those created before verification existed, are verified.
+var KeyEmailUnverified = "email_unverified:%s"
+
+type IEmailVerificationRepository interface {
+	StoreEmailVerificationToken(ctx context.Context, email string, tokenHash string, ttl time.Duration) error
+	ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (string, error)
+	IsEmailUnverified(ctx context.Context, email string) (bool, error)
+	AcquireEmailVerificationCooldown(ctx context.Context, email string, cooldown time.Duration) (bool, error)
+}
+
+type RedisEmailVerificationRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisEmailVerificationRepository(rc *redis.Client) *RedisEmailVerificationRepository {
+	return &RedisEmailVerificationRepository{
+		RC: rc,
+	}
+}
+
+// StoreEmailVerificationToken marks email unverified and stores tokenHash for it, replacing the token issued before
+func (er *RedisEmailVerificationRepository) StoreEmailVerificationToken(ctx context.Context, email string, tokenHash string, ttl time.Duration) error {
+	byEmailKey := fmt.Sprintf(KeyEmailVerificationByEmail, email)
+	previousTokenHash, err := er.RC.Get(ctx, byEmailKey).Result()
+	if err != nil && err != redis.Nil {
+		return apperror.ErrInternal
+	}
+
+	_, err = er.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previousTokenHash != "" {
+			pipe.Del(ctx, fmt.Sprintf(KeyEmailVerification, previousTokenHash))
+		}
+		pipe.Set(ctx, fmt.Sprintf(KeyEmailUnverified, email), 1, 0)
+		pipe.Set(ctx, fmt.Sprintf(KeyEmailVerification, tokenHash), email, ttl)
+		pipe.Set(ctx, byEmailKey, tokenHash, ttl)
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// ConsumeEmailVerificationToken marks the email tokenHash was issued to verified and returns it, empty when
+// the token doesn't exist, has expired or was already used
+func (er *RedisEmailVerificationRepository) ConsumeEmailVerificationToken(ctx context.Context, tokenHash string) (string, error) {
+	email, err := er.RC.GetDel(ctx, fmt.Sprintf(KeyEmailVerification, tokenHash)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", apperror.ErrInternal
+	}
+
+	err = er.RC.Del(ctx, fmt.Sprintf(KeyEmailUnverified, email), fmt.Sprintf(KeyEmailVerificationByEmail, email)).Err()
+	if err != nil {
+		return email, apperror.ErrInternal
+	}
+	return email, nil
+}
+
+func (er *RedisEmailVerificationRepository) IsEmailUnverified(ctx context.Context, email string) (bool, error) {
+	count, err := er.RC.Exists(ctx, fmt.Sprintf(KeyEmailUnverified, email)).Result()
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return count > 0, nil
+}
+
+// AcquireEmailVerificationCooldown reports false when a verification mail was already sent to email within cooldown
+func (er *RedisEmailVerificationRepository) AcquireEmailVerificationCooldown(ctx context.Context, email string, cooldown time.Duration) (bool, error) {
+	isAcquired, err := er.RC.SetNX(ctx, fmt.Sprintf(KeyEmailVerificationCooldown, email), 1, cooldown).Result()
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return isAcquired, nil
+}
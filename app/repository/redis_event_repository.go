@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyEvents = "events:%d"
+
+type IEventRepository interface {
+	PublishEvent(ctx context.Context, userID int64, event model.Event) error
+	SubscribeEvents(ctx context.Context, userID int64) (model.EventSubscription, error)
+}
+
+// RedisEventRepository fans events out through redis pub/sub so every core instance holding
+// a connection of the user receives them
+type RedisEventRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisEventRepository(rc *redis.Client) *RedisEventRepository {
+	return &RedisEventRepository{
+		RC: rc,
+	}
+}
+
+func (er *RedisEventRepository) PublishEvent(ctx context.Context, userID int64, event model.Event) error {
+	jsonData, _ := json.Marshal(event)
+	err := er.RC.Publish(ctx, fmt.Sprintf(KeyEvents, userID), jsonData).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+func (er *RedisEventRepository) SubscribeEvents(ctx context.Context, userID int64) (model.EventSubscription, error) {
+	pubSub := er.RC.Subscribe(ctx, fmt.Sprintf(KeyEvents, userID))
+	// wait for subscription confirmation so no event published afterwards is missed
+	_, err := pubSub.Receive(ctx)
+	if err != nil {
+		pubSub.Close()
+		return model.EventSubscription{}, apperror.ErrInternal
+	}
+
+	events := make(chan model.Event)
+	go func() {
+		defer close(events)
+		for msg := range pubSub.Channel() {
+			var event model.Event
+			if json.Unmarshal([]byte(msg.Payload), &event) != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return model.EventSubscription{
+		UserID: userID,
+		Events: events,
+		Close:  pubSub.Close,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyIdempotency = "idempotency:%s"
+
+// completeIdempotencyScript replaces the record only while it is still held by the owner in ARGV[1]
+var completeIdempotencyScript = redis.NewScript(`
+local stored = redis.call('GET', KEYS[1])
+if not stored or cjson.decode(stored).owner ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// releaseIdempotencyScript deletes the record only while it is still held by the owner in ARGV[1]
+var releaseIdempotencyScript = redis.NewScript(`
+local stored = redis.call('GET', KEYS[1])
+if not stored or cjson.decode(stored).owner ~= ARGV[1] then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+
+type IIdempotencyRepository interface {
+	AcquireIdempotencyKey(ctx context.Context, key string, record model.IdempotencyRecord, ttl time.Duration) (model.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, owner string, record model.IdempotencyRecord, ttl time.Duration) (bool, error)
+	ReleaseIdempotencyKey(ctx context.Context, key string, owner string) (bool, error)
+}
+
+type RedisIdempotencyRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisIdempotencyRepository(rc *redis.Client) *RedisIdempotencyRepository {
+	return &RedisIdempotencyRepository{
+		RC: rc,
+	}
+}
+
+// AcquireIdempotencyKey stores record when key is unused and reports true. Otherwise the record already
+// stored under key is returned and false is reported.
+func (ir *RedisIdempotencyRepository) AcquireIdempotencyKey(ctx context.Context, key string, record model.IdempotencyRecord, ttl time.Duration) (model.IdempotencyRecord, bool, error) {
+	jsonData, _ := json.Marshal(record)
+	isAcquired, err := ir.RC.SetNX(ctx, fmt.Sprintf(KeyIdempotency, key), jsonData, ttl).Result()
+	if err != nil {
+		return record, false, apperror.ErrInternal
+	}
+	if isAcquired {
+		return record, true, nil
+	}
+
+	existingJSONData, err := ir.RC.Get(ctx, fmt.Sprintf(KeyIdempotency, key)).Result()
+	if err == redis.Nil {
+		// expired in between, let the caller try again
+		return record, false, apperror.ErrIdempotencyKeyInUse
+	}
+	if err != nil {
+		return record, false, apperror.ErrInternal
+	}
+
+	var existing model.IdempotencyRecord
+	json.Unmarshal([]byte(existingJSONData), &existing)
+	return existing, false, nil
+}
+
+// CompleteIdempotencyKey replaces the record held by owner with record and reports true. False is reported when the
+// key expired or was acquired by another request in the meantime, that record is left untouched.
+func (ir *RedisIdempotencyRepository) CompleteIdempotencyKey(ctx context.Context, key string, owner string, record model.IdempotencyRecord, ttl time.Duration) (bool, error) {
+	jsonData, _ := json.Marshal(record)
+	isCompleted, err := completeIdempotencyScript.Run(ctx, ir.RC, []string{fmt.Sprintf(KeyIdempotency, key)}, owner, jsonData, ttl.Milliseconds()).Bool()
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return isCompleted, nil
+}
+
+// ReleaseIdempotencyKey deletes the record held by owner and reports true, like CompleteIdempotencyKey a record
+// of another request is left untouched
+func (ir *RedisIdempotencyRepository) ReleaseIdempotencyKey(ctx context.Context, key string, owner string) (bool, error) {
+	isReleased, err := releaseIdempotencyScript.Run(ctx, ir.RC, []string{fmt.Sprintf(KeyIdempotency, key)}, owner).Bool()
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return isReleased, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestIdempotencyKeyExpiredLockIsNotOverwritten(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+	repo := NewRedisIdempotencyRepository(rc)
+	ctx := context.Background()
+
+	lock := func(owner string) model.IdempotencyRecord {
+		return model.IdempotencyRecord{Status: util.IdempotencyStatusProcessing, Owner: owner, Fingerprint: "f"}
+	}
+	_, isAcquired, err := repo.AcquireIdempotencyKey(ctx, "k", lock("first"), time.Minute)
+	if err != nil || !isAcquired {
+		t.Fatalf("first acquire: %v %v", isAcquired, err)
+	}
+
+	// the first request outlives its lock and a retry takes the key over
+	mr.FastForward(2 * time.Minute)
+	_, isAcquired, err = repo.AcquireIdempotencyKey(ctx, "k", lock("retry"), time.Minute)
+	if err != nil || !isAcquired {
+		t.Fatalf("retry acquire: %v %v", isAcquired, err)
+	}
+
+	completed := model.IdempotencyRecord{Status: util.IdempotencyStatusCompleted, Fingerprint: "f", StatusCode: 200}
+	isCompleted, err := repo.CompleteIdempotencyKey(ctx, "k", "first", completed, time.Hour)
+	if err != nil || isCompleted {
+		t.Fatalf("stale owner completed the key: %v %v", isCompleted, err)
+	}
+	isReleased, err := repo.ReleaseIdempotencyKey(ctx, "k", "first")
+	if err != nil || isReleased {
+		t.Fatalf("stale owner released the key: %v %v", isReleased, err)
+	}
+	record, isAcquired, err := repo.AcquireIdempotencyKey(ctx, "k", lock("other"), time.Minute)
+	if err != nil || isAcquired || record.Owner != "retry" {
+		t.Fatalf("got record of %q, acquired %v, error %v, want the lock of the retry", record.Owner, isAcquired, err)
+	}
+
+	isCompleted, err = repo.CompleteIdempotencyKey(ctx, "k", "retry", completed, time.Hour)
+	if err != nil || !isCompleted {
+		t.Fatalf("owner could not complete the key: %v %v", isCompleted, err)
+	}
+	record, _, err = repo.AcquireIdempotencyKey(ctx, "k", lock("other"), time.Minute)
+	if err != nil || record.Status != util.IdempotencyStatusCompleted {
+		t.Fatalf("got record %+v, error %v, want the completed one", record, err)
+	}
+	if ttl := mr.TTL(fmt.Sprintf(KeyIdempotency, "k")); ttl != time.Hour {
+		t.Fatalf("completed record ttl %s, want 1h", ttl)
+	}
+}
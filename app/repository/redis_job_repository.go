@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyDelayedJobs = "delayed_jobs"
+var KeyDeadJobs = "delayed_jobs:dead"
+
+// leaseDueJobsScript hands due jobs to a single worker by pushing them back by the lease in ARGV[3]. They stay
+// queued, so a job whose worker stops before completing it runs again once the lease is over.
+var leaseDueJobsScript = redis.NewScript(`
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for i = 1, #jobs do
+	redis.call('ZADD', KEYS[1], 'XX', ARGV[3], jobs[i])
+end
+return jobs
+`)
+
+type IJobRepository interface {
+	ScheduleJob(ctx context.Context, job model.DelayedJob) error
+	LeaseDueJobs(ctx context.Context, now time.Time, limit int64, lease time.Duration) ([]model.DelayedJob, error)
+	CompleteJob(ctx context.Context, job model.DelayedJob) error
+	RescheduleJob(ctx context.Context, job model.DelayedJob, next model.DelayedJob) error
+	BuryJob(ctx context.Context, job model.DelayedJob, buriedAt time.Time) error
+}
+
+type RedisJobRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisJobRepository(rc *redis.Client) *RedisJobRepository {
+	return &RedisJobRepository{
+		RC: rc,
+	}
+}
+
+// ScheduleJob queues job to run at job.RunAt, scheduling the same job twice keeps a single entry
+func (jr *RedisJobRepository) ScheduleJob(ctx context.Context, job model.DelayedJob) error {
+	jsonData, _ := json.Marshal(job)
+	err := jr.RC.ZAdd(ctx, KeyDelayedJobs, redis.Z{Score: float64(job.RunAt.UnixMilli()), Member: jsonData}).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// LeaseDueJobs returns up to limit jobs due at now, they are handed to no other worker for lease. A leased job
+// is taken off the queue by CompleteJob, RescheduleJob or BuryJob.
+func (jr *RedisJobRepository) LeaseDueJobs(ctx context.Context, now time.Time, limit int64, lease time.Duration) ([]model.DelayedJob, error) {
+	members, err := leaseDueJobsScript.Run(ctx, jr.RC, []string{KeyDelayedJobs}, now.UnixMilli(), limit, now.Add(lease).UnixMilli()).StringSlice()
+	if err != nil && err != redis.Nil {
+		return nil, apperror.ErrInternal
+	}
+
+	jobs := make([]model.DelayedJob, 0, len(members))
+	for i := 0; i < len(members); i++ {
+		var job model.DelayedJob
+		if json.Unmarshal([]byte(members[i]), &job) != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// CompleteJob takes a job that ran off the queue
+func (jr *RedisJobRepository) CompleteJob(ctx context.Context, job model.DelayedJob) error {
+	jsonData, _ := json.Marshal(job)
+	err := jr.RC.ZRem(ctx, KeyDelayedJobs, jsonData).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// RescheduleJob replaces a job that failed with next in one step
+func (jr *RedisJobRepository) RescheduleJob(ctx context.Context, job model.DelayedJob, next model.DelayedJob) error {
+	jsonData, _ := json.Marshal(job)
+	nextJSONData, _ := json.Marshal(next)
+	_, err := jr.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, KeyDelayedJobs, jsonData)
+		pipe.ZAdd(ctx, KeyDelayedJobs, redis.Z{Score: float64(next.RunAt.UnixMilli()), Member: nextJSONData})
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// BuryJob moves a job that kept failing to the dead job list, where it is kept to be inspected and queued again
+func (jr *RedisJobRepository) BuryJob(ctx context.Context, job model.DelayedJob, buriedAt time.Time) error {
+	jsonData, _ := json.Marshal(job)
+	_, err := jr.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, KeyDelayedJobs, jsonData)
+		pipe.ZAdd(ctx, KeyDeadJobs, redis.Z{Score: float64(buriedAt.UnixMilli()), Member: jsonData})
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLeasedJobRunsAgainUntilCompleted(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+	repo := NewRedisJobRepository(rc)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	job := model.DelayedJob{Type: util.JobTypeExpireSubscription, Email: "a@b.c", ProductCode: "SKU001", RunAt: now}
+	err := repo.ScheduleJob(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := repo.LeaseDueJobs(ctx, now, 10, time.Minute)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("got %d jobs, error %v, want the due job", len(jobs), err)
+	}
+	jobs, err = repo.LeaseDueJobs(ctx, now.Add(30*time.Second), 10, time.Minute)
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("got %d jobs, error %v, want none while leased", len(jobs), err)
+	}
+
+	// the worker stopped without completing it
+	jobs, err = repo.LeaseDueJobs(ctx, now.Add(time.Minute), 10, time.Minute)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("got %d jobs, error %v, want the job again once the lease is over", len(jobs), err)
+	}
+	err = repo.CompleteJob(ctx, jobs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err = repo.LeaseDueJobs(ctx, now.Add(time.Hour), 10, time.Minute)
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("got %d jobs, error %v, want none once completed", len(jobs), err)
+	}
+}
+
+func TestFailedJobIsRescheduledThenBuried(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+	repo := NewRedisJobRepository(rc)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	job := model.DelayedJob{Type: util.JobTypeExpireSubscription, Email: "a@b.c", ProductCode: "SKU001", RunAt: now}
+	err := repo.ScheduleJob(ctx, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := repo.LeaseDueJobs(ctx, now, 10, time.Hour)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("got %d jobs, error %v, want the due job", len(jobs), err)
+	}
+
+	next := jobs[0]
+	next.Attempt++
+	next.RunAt = now.Add(time.Minute)
+	err = repo.RescheduleJob(ctx, jobs[0], next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err = repo.LeaseDueJobs(ctx, now.Add(time.Minute), 10, time.Hour)
+	if err != nil || len(jobs) != 1 || jobs[0].Attempt != 1 {
+		t.Fatalf("got %+v, error %v, want only the retry", jobs, err)
+	}
+
+	err = repo.BuryJob(ctx, jobs[0], now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err = repo.LeaseDueJobs(ctx, now.Add(2*time.Hour), 10, time.Hour)
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("got %d jobs, error %v, want none once buried", len(jobs), err)
+	}
+	dead, err := rc.ZCard(ctx, KeyDeadJobs).Result()
+	if err != nil || dead != 1 {
+		t.Fatalf("got %d dead jobs, error %v, want the buried job kept", dead, err)
+	}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyLoginFailures = "login_failures:%s"
+var KeyLoginLockout = "login_lockout:%s"
+
+// recordLoginFailureScript KEYS: failures counter. ARGV: window in ms, the counter expires a window after the first failure
+var recordLoginFailureScript = redis.NewScript(`
+local failures = redis.call('INCR', KEYS[1])
+if failures == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return failures
+`)
+
+// ILoginAttemptRepository tracks failed logins per subject, a subject is an email or an IP prefixed by its kind
+type ILoginAttemptRepository interface {
+	GetLoginLockout(ctx context.Context, subjects ...string) (time.Duration, error)
+	RecordLoginFailure(ctx context.Context, subject string, window time.Duration) (int64, error)
+	LockLogin(ctx context.Context, subject string, duration time.Duration) error
+	ResetLoginFailures(ctx context.Context, subject string) error
+}
+
+type RedisLoginAttemptRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisLoginAttemptRepository(rc *redis.Client) *RedisLoginAttemptRepository {
+	return &RedisLoginAttemptRepository{
+		RC: rc,
+	}
+}
+
+// GetLoginLockout returns the longest remaining lockout among subjects, 0 when none is locked
+func (lr *RedisLoginAttemptRepository) GetLoginLockout(ctx context.Context, subjects ...string) (time.Duration, error) {
+	pipe := lr.RC.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(subjects))
+	for i, subject := range subjects {
+		ttls[i] = pipe.PTTL(ctx, fmt.Sprintf(KeyLoginLockout, subject))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, apperror.ErrInternal
+	}
+
+	var lockout time.Duration
+	for _, ttl := range ttls {
+		// missing keys report a negative ttl
+		if ttl.Val() > lockout {
+			lockout = ttl.Val()
+		}
+	}
+	return lockout, nil
+}
+
+// RecordLoginFailure counts a failed login of subject and returns the failures within window
+func (lr *RedisLoginAttemptRepository) RecordLoginFailure(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	failures, err := recordLoginFailureScript.Run(ctx, lr.RC, []string{fmt.Sprintf(KeyLoginFailures, subject)}, window.Milliseconds()).Int64()
+	if err != nil {
+		return 0, apperror.ErrInternal
+	}
+	return failures, nil
+}
+
+func (lr *RedisLoginAttemptRepository) LockLogin(ctx context.Context, subject string, duration time.Duration) error {
+	err := lr.RC.Set(ctx, fmt.Sprintf(KeyLoginLockout, subject), 1, duration).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+func (lr *RedisLoginAttemptRepository) ResetLoginFailures(ctx context.Context, subject string) error {
+	err := lr.RC.Del(ctx, fmt.Sprintf(KeyLoginFailures, subject)).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyOrder = "order:%s"
+
+type IOrderRepository interface {
+	StoreOrder(ctx context.Context, order model.Order) error
+	GetOrder(ctx context.Context, id string) (model.Order, error)
+	TransitionOrderStatus(ctx context.Context, id string, from string, to string, updatedAt time.Time) (model.Order, error)
+	ClaimStaleOrder(ctx context.Context, id string, status string, staleBefore time.Time, claimedAt time.Time) (model.Order, error)
+}
+
+type RedisOrderRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisOrderRepository(rc *redis.Client) *RedisOrderRepository {
+	return &RedisOrderRepository{
+		RC: rc,
+	}
+}
+
+func (or *RedisOrderRepository) StoreOrder(ctx context.Context, order model.Order) error {
+	jsonData, _ := json.Marshal(order)
+	err := or.RC.Set(ctx, fmt.Sprintf(KeyOrder, order.ID), jsonData, 0).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+func (or *RedisOrderRepository) GetOrder(ctx context.Context, id string) (model.Order, error) {
+	jsonData, err := or.RC.Get(ctx, fmt.Sprintf(KeyOrder, id)).Result()
+	if err == redis.Nil {
+		return model.Order{}, apperror.ErrOrderNotFound
+	}
+	if err != nil {
+		return model.Order{}, apperror.ErrInternal
+	}
+
+	var order model.Order
+	json.Unmarshal([]byte(jsonData), &order)
+	return order, nil
+}
+
+// TransitionOrderStatus moves the order from one status to another, failing with ErrOrderStatusConflict
+// when the order is not in the from status anymore, e.g. because a duplicate callback got there first
+func (or *RedisOrderRepository) TransitionOrderStatus(ctx context.Context, id string, from string, to string, updatedAt time.Time) (model.Order, error) {
+	return or.updateOrder(ctx, id, func(order *model.Order) error {
+		if order.Status != from {
+			return apperror.ErrOrderStatusConflict
+		}
+		order.Status = to
+		order.UpdatedAt = updatedAt
+		return nil
+	})
+}
+
+// ClaimStaleOrder takes over an order in status that wasn't updated since staleBefore by setting its UpdatedAt to
+// claimedAt, failing with ErrOrderStatusConflict when it isn't stale, so a stuck order is recovered only once
+func (or *RedisOrderRepository) ClaimStaleOrder(ctx context.Context, id string, status string, staleBefore time.Time, claimedAt time.Time) (model.Order, error) {
+	return or.updateOrder(ctx, id, func(order *model.Order) error {
+		if order.Status != status || order.UpdatedAt.After(staleBefore) {
+			return apperror.ErrOrderStatusConflict
+		}
+		order.UpdatedAt = claimedAt
+		return nil
+	})
+}
+
+// updateOrder applies update to the stored order, failing with ErrOrderStatusConflict when the order changed
+// in between
+func (or *RedisOrderRepository) updateOrder(ctx context.Context, id string, update func(order *model.Order) error) (model.Order, error) {
+	key := fmt.Sprintf(KeyOrder, id)
+	var order model.Order
+	err := or.RC.Watch(ctx, func(tx *redis.Tx) error {
+		jsonData, err := tx.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return apperror.ErrOrderNotFound
+		}
+		if err != nil {
+			return apperror.ErrInternal
+		}
+
+		json.Unmarshal([]byte(jsonData), &order)
+		err = update(&order)
+		if err != nil {
+			return err
+		}
+
+		newJSONData, _ := json.Marshal(order)
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, newJSONData, 0)
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		return order, apperror.ErrOrderStatusConflict
+	}
+	if err != nil {
+		if errors.Is(err, apperror.ErrOrderNotFound) || errors.Is(err, apperror.ErrOrderStatusConflict) {
+			return order, err
+		}
+		return order, apperror.ErrInternal
+	}
+	return order, nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyPasswordReset = "password_reset:%s"
+var KeyPasswordResetByEmail = "password_reset_by_email:%s"
+var KeyPasswordResetCooldown = "password_reset_cooldown:%s"
+
+type IPasswordResetRepository interface {
+	StorePasswordResetToken(ctx context.Context, email string, tokenHash string, ttl time.Duration) error
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error)
+	AcquirePasswordResetCooldown(ctx context.Context, email string, cooldown time.Duration) (bool, error)
+}
+
+type RedisPasswordResetRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisPasswordResetRepository(rc *redis.Client) *RedisPasswordResetRepository {
+	return &RedisPasswordResetRepository{
+		RC: rc,
+	}
+}
+
+// StorePasswordResetToken stores tokenHash for email, replacing the token issued to email before
+func (pr *RedisPasswordResetRepository) StorePasswordResetToken(ctx context.Context, email string, tokenHash string, ttl time.Duration) error {
+	byEmailKey := fmt.Sprintf(KeyPasswordResetByEmail, email)
+	previousTokenHash, err := pr.RC.Get(ctx, byEmailKey).Result()
+	if err != nil && err != redis.Nil {
+		return apperror.ErrInternal
+	}
+
+	_, err = pr.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previousTokenHash != "" {
+			pipe.Del(ctx, fmt.Sprintf(KeyPasswordReset, previousTokenHash))
+		}
+		pipe.Set(ctx, fmt.Sprintf(KeyPasswordReset, tokenHash), email, ttl)
+		pipe.Set(ctx, byEmailKey, tokenHash, ttl)
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// ConsumePasswordResetToken deletes tokenHash and returns the email it was issued to, empty when it
+// doesn't exist, has expired or was already used
+func (pr *RedisPasswordResetRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (string, error) {
+	email, err := pr.RC.GetDel(ctx, fmt.Sprintf(KeyPasswordReset, tokenHash)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", apperror.ErrInternal
+	}
+
+	err = pr.RC.Del(ctx, fmt.Sprintf(KeyPasswordResetByEmail, email)).Err()
+	if err != nil {
+		return email, apperror.ErrInternal
+	}
+	return email, nil
+}
+
+// AcquirePasswordResetCooldown reports false when a reset was already requested for email within cooldown
+func (pr *RedisPasswordResetRepository) AcquirePasswordResetCooldown(ctx context.Context, email string, cooldown time.Duration) (bool, error) {
+	isAcquired, err := pr.RC.SetNX(ctx, fmt.Sprintf(KeyPasswordResetCooldown, email), 1, cooldown).Result()
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return isAcquired, nil
+}
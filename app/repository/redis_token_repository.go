@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/redis/go-redis/v9"
+)
+
+var KeyValidToken = "valid_token:%s"
+var KeyTokenSeen = "token_seen:%s"
+var KeyRevokedToken = "revoked_token:%s"
+var KeyTokensRevokedBefore = "tokens_revoked_before:%s"
+var KeyRefreshTokenFamily = "refresh_token_family:%s"
+var KeyAccessTokenFamily = "access_token_family:%s"
+var KeyWSTicket = "ws_ticket:%s"
+
+const refreshTokenRotated = 1
+
+// rotateRefreshTokenScript KEYS: family hash, family link of the new access token. ARGV: presented refresh token
+// hash, new refresh token hash, new access token hash, ttl in ms, family id, access token ttl in ms. Returns 0
+// without touching the family when the presented token is not the latest of the family anymore.
+var rotateRefreshTokenScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'is_revoked') ~= '0' or redis.call('HGET', KEYS[1], 'refresh_token_hash') ~= ARGV[1] then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'refresh_token_hash', ARGV[2], 'access_token_hash', ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+redis.call('SET', KEYS[2], ARGV[5], 'PX', ARGV[6])
+return 1
+`)
+
+// revokeRefreshTokenFamilyScript KEYS: family hash. Only existing families are marked so expired ones don't come back.
+var revokeRefreshTokenFamilyScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('HSET', KEYS[1], 'is_revoked', '1')
+end
+return 1
+`)
+
+type ITokenRepository interface {
+	StoreValidToken(ctx context.Context, tokenHash string, tokenInfo model.TokenInfo, ttl time.Duration) error
+	GetValidToken(ctx context.Context, tokenHash string) (model.TokenInfo, error)
+	MarkTokenSeen(ctx context.Context, tokenHash string, seenAt time.Time, ttl time.Duration) (time.Time, error)
+	RevokeToken(ctx context.Context, tokenHash string, ttl time.Duration) error
+	RevokeTokensBefore(ctx context.Context, email string, before time.Time, ttl time.Duration) error
+	GetTokenRevocation(ctx context.Context, tokenHash string, email string) (model.TokenRevocation, error)
+	StoreRefreshTokenFamily(ctx context.Context, family model.RefreshTokenFamily, ttl time.Duration, accessTokenTTL time.Duration) error
+	GetRefreshTokenFamily(ctx context.Context, familyID string) (model.RefreshTokenFamily, error)
+	RotateRefreshToken(ctx context.Context, familyID string, refreshTokenHash string, newRefreshTokenHash string, newAccessTokenHash string, ttl time.Duration, accessTokenTTL time.Duration) (bool, error)
+	GetAccessTokenFamilyID(ctx context.Context, accessTokenHash string) (string, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	StoreWSTicket(ctx context.Context, ticketHash string, principal model.Principal, ttl time.Duration) error
+	ConsumeWSTicket(ctx context.Context, ticketHash string) (model.Principal, error)
+}
+
+type RedisTokenRepository struct {
+	RC *redis.Client
+}
+
+func NewRedisTokenRepository(rc *redis.Client) *RedisTokenRepository {
+	return &RedisTokenRepository{
+		RC: rc,
+	}
+}
+
+func (tr *RedisTokenRepository) StoreValidToken(ctx context.Context, tokenHash string, tokenInfo model.TokenInfo, ttl time.Duration) error {
+	jsonData, _ := json.Marshal(tokenInfo)
+	err := tr.RC.Set(ctx, fmt.Sprintf(KeyValidToken, tokenHash), jsonData, ttl).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// GetValidToken returns an empty TokenInfo when the token is not cached
+func (tr *RedisTokenRepository) GetValidToken(ctx context.Context, tokenHash string) (model.TokenInfo, error) {
+	var tokenInfo model.TokenInfo
+	jsonData, err := tr.RC.Get(ctx, fmt.Sprintf(KeyValidToken, tokenHash)).Result()
+	if err == redis.Nil {
+		return tokenInfo, nil
+	}
+	if err != nil {
+		return tokenInfo, apperror.ErrInternal
+	}
+
+	json.Unmarshal([]byte(jsonData), &tokenInfo)
+	return tokenInfo, nil
+}
+
+// MarkTokenSeen records seenAt as the first time tokenHash was seen and returns the time recorded first
+func (tr *RedisTokenRepository) MarkTokenSeen(ctx context.Context, tokenHash string, seenAt time.Time, ttl time.Duration) (time.Time, error) {
+	key := fmt.Sprintf(KeyTokenSeen, tokenHash)
+	isSet, err := tr.RC.SetNX(ctx, key, seenAt.UnixMilli(), ttl).Result()
+	if err != nil {
+		return seenAt, apperror.ErrInternal
+	}
+	if isSet {
+		return seenAt, nil
+	}
+
+	firstSeenAt, err := tr.RC.Get(ctx, key).Int64()
+	if err != nil {
+		return seenAt, apperror.ErrInternal
+	}
+	return time.UnixMilli(firstSeenAt), nil
+}
+
+// RevokeToken keeps tokenHash revoked for ttl, it should last until the token expires anyway
+func (tr *RedisTokenRepository) RevokeToken(ctx context.Context, tokenHash string, ttl time.Duration) error {
+	_, err := tr.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, fmt.Sprintf(KeyRevokedToken, tokenHash), 1, ttl)
+		pipe.Del(ctx, fmt.Sprintf(KeyValidToken, tokenHash))
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// RevokeTokensBefore revokes every token of email issued until before. ttl should be the longest
+// lifetime of a token, afterwards all of them have expired anyway.
+func (tr *RedisTokenRepository) RevokeTokensBefore(ctx context.Context, email string, before time.Time, ttl time.Duration) error {
+	err := tr.RC.Set(ctx, fmt.Sprintf(KeyTokensRevokedBefore, email), before.UnixMilli(), ttl).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+func (tr *RedisTokenRepository) GetTokenRevocation(ctx context.Context, tokenHash string, email string) (model.TokenRevocation, error) {
+	var revocation model.TokenRevocation
+	pipe := tr.RC.Pipeline()
+	isRevoked := pipe.Exists(ctx, fmt.Sprintf(KeyRevokedToken, tokenHash))
+	revokedBefore := pipe.Get(ctx, fmt.Sprintf(KeyTokensRevokedBefore, email))
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return revocation, apperror.ErrInternal
+	}
+
+	revocation.IsRevoked = isRevoked.Val() > 0
+	if revokedBeforeMs, err := strconv.ParseInt(revokedBefore.Val(), 10, 64); err == nil {
+		revocation.RevokedBefore = time.UnixMilli(revokedBeforeMs)
+	}
+	return revocation, nil
+}
+
+// StoreRefreshTokenFamily stores a new family for ttl and links its access token to it for accessTokenTTL
+func (tr *RedisTokenRepository) StoreRefreshTokenFamily(ctx context.Context, family model.RefreshTokenFamily, ttl time.Duration, accessTokenTTL time.Duration) error {
+	familyKey := fmt.Sprintf(KeyRefreshTokenFamily, family.ID)
+	_, err := tr.RC.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, familyKey,
+			"email", family.Email,
+			"refresh_token_hash", family.RefreshTokenHash,
+			"access_token_hash", family.AccessTokenHash,
+			"is_revoked", "0",
+			"created_at", family.CreatedAt.UnixMilli())
+		pipe.PExpire(ctx, familyKey, ttl)
+		pipe.Set(ctx, fmt.Sprintf(KeyAccessTokenFamily, family.AccessTokenHash), family.ID, accessTokenTTL)
+		return nil
+	})
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// GetRefreshTokenFamily returns an empty family when it doesn't exist or has expired
+func (tr *RedisTokenRepository) GetRefreshTokenFamily(ctx context.Context, familyID string) (model.RefreshTokenFamily, error) {
+	var family model.RefreshTokenFamily
+	data, err := tr.RC.HGetAll(ctx, fmt.Sprintf(KeyRefreshTokenFamily, familyID)).Result()
+	if err != nil && err != redis.Nil {
+		return family, apperror.ErrInternal
+	}
+	if len(data) == 0 {
+		return family, nil
+	}
+
+	createdAt, _ := strconv.ParseInt(data["created_at"], 10, 64)
+	family = model.RefreshTokenFamily{
+		ID:               familyID,
+		Email:            data["email"],
+		RefreshTokenHash: data["refresh_token_hash"],
+		AccessTokenHash:  data["access_token_hash"],
+		IsRevoked:        data["is_revoked"] != "0",
+		CreatedAt:        time.UnixMilli(createdAt),
+	}
+	return family, nil
+}
+
+// RotateRefreshToken replaces refreshTokenHash, which must be the latest of the family, with newRefreshTokenHash.
+// It reports false when another request rotated the family first or it was revoked in between.
+func (tr *RedisTokenRepository) RotateRefreshToken(ctx context.Context, familyID string, refreshTokenHash string, newRefreshTokenHash string, newAccessTokenHash string, ttl time.Duration, accessTokenTTL time.Duration) (bool, error) {
+	keys := []string{fmt.Sprintf(KeyRefreshTokenFamily, familyID), fmt.Sprintf(KeyAccessTokenFamily, newAccessTokenHash)}
+	result, err := rotateRefreshTokenScript.Run(ctx, tr.RC, keys, refreshTokenHash, newRefreshTokenHash, newAccessTokenHash,
+		ttl.Milliseconds(), familyID, accessTokenTTL.Milliseconds()).Int64()
+	if err != nil {
+		return false, apperror.ErrInternal
+	}
+	return result == refreshTokenRotated, nil
+}
+
+// GetAccessTokenFamilyID returns the family the access token was issued in, empty when unknown
+func (tr *RedisTokenRepository) GetAccessTokenFamilyID(ctx context.Context, accessTokenHash string) (string, error) {
+	familyID, err := tr.RC.Get(ctx, fmt.Sprintf(KeyAccessTokenFamily, accessTokenHash)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", apperror.ErrInternal
+	}
+	return familyID, nil
+}
+
+func (tr *RedisTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	err := revokeRefreshTokenFamilyScript.Run(ctx, tr.RC, []string{fmt.Sprintf(KeyRefreshTokenFamily, familyID)}).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// StoreWSTicket stores the principal a websocket ticket was issued to
+func (tr *RedisTokenRepository) StoreWSTicket(ctx context.Context, ticketHash string, principal model.Principal, ttl time.Duration) error {
+	jsonData, _ := json.Marshal(principal)
+	err := tr.RC.Set(ctx, fmt.Sprintf(KeyWSTicket, ticketHash), jsonData, ttl).Err()
+	if err != nil {
+		return apperror.ErrInternal
+	}
+	return nil
+}
+
+// ConsumeWSTicket deletes ticketHash and returns the principal it was issued to, empty when it doesn't exist,
+// has expired or was already used
+func (tr *RedisTokenRepository) ConsumeWSTicket(ctx context.Context, ticketHash string) (model.Principal, error) {
+	var principal model.Principal
+	jsonData, err := tr.RC.GetDel(ctx, fmt.Sprintf(KeyWSTicket, ticketHash)).Bytes()
+	if err == redis.Nil {
+		return principal, nil
+	}
+	if err != nil {
+		return principal, apperror.ErrInternal
+	}
+
+	err = json.Unmarshal(jsonData, &principal)
+	if err != nil {
+		return principal, apperror.ErrInternal
+	}
+	return principal, nil
+}
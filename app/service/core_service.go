@@ -5,73 +5,142 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"time"
 
+	"github.com/atrariksa/kenalan-core/app/apperror"
 	"github.com/atrariksa/kenalan-core/app/model"
 	"github.com/atrariksa/kenalan-core/app/repository"
 	"github.com/atrariksa/kenalan-core/app/util"
 	"github.com/atrariksa/kenalan-core/config"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/status"
 
 	pb "github.com/atrariksa/kenalan-core/app/external/grpc_client"
+	"github.com/atrariksa/kenalan-core/app/external/jwt_verifier"
+	"github.com/atrariksa/kenalan-core/app/external/mailer"
+	"github.com/atrariksa/kenalan-core/app/external/payment_gateway"
 )
 
 var KeyViewProfile = "view_profile:%s"
+var KeySwipeQuota = "swipe_quota:%s"
+var KeyViewedProfileIDs = "viewed_profiles:%s"
 
 type ICoreService interface {
+	Authenticate(ctx context.Context, token string) (model.Principal, error)
 	SignUp(ctx context.Context, signUpRequest model.SignUpRequest) error
-	Login(ctx context.Context, loginRequest model.LoginRequest) (string, error)
-	ViewProfile(ctx context.Context, vpRequest model.ViewProfileRequest) (model.Profile, error)
-	Purchase(ctx context.Context, pr model.PurchaseRequest) error
+	Login(ctx context.Context, loginRequest model.LoginRequest) (model.TokenPair, error)
+	RefreshToken(ctx context.Context, rtr model.RefreshTokenRequest) (model.TokenPair, error)
+	ForgotPassword(ctx context.Context, fpr model.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, rpr model.ResetPasswordRequest) error
+	VerifyEmail(ctx context.Context, ver model.VerifyEmailRequest) error
+	ResendVerificationEmail(ctx context.Context, rver model.ResendVerificationEmailRequest) error
+	Logout(ctx context.Context, principal model.Principal, allDevices bool) error
+	IssueWSTicket(ctx context.Context, principal model.Principal) (string, error)
+	AuthenticateWSTicket(ctx context.Context, ticket string) (model.Principal, error)
+	ViewProfile(ctx context.Context, vpRequest model.ViewProfileRequest) (model.ViewProfileResult, error)
+	GetMatches(ctx context.Context, mr model.MatchesRequest) ([]model.Match, int64, error)
+	Unmatch(ctx context.Context, ur model.UnmatchRequest) error
+	GetQuota(ctx context.Context, qr model.QuotaRequest) (model.QuotaStatus, error)
+	SendMessage(ctx context.Context, smr model.SendMessageRequest) (model.Message, error)
+	GetMessages(ctx context.Context, mr model.MessagesRequest) ([]model.Message, int64, error)
+	MarkMessagesRead(ctx context.Context, mmr model.MarkMessagesReadRequest) error
+	GetLikes(ctx context.Context, lr model.LikesRequest) ([]model.Like, int64, error)
+	LikeBack(ctx context.Context, lbr model.LikeBackRequest) (*model.Match, error)
+	SubscribeEvents(ctx context.Context, principal model.Principal) (model.EventSubscription, error)
+	SendTyping(ctx context.Context, userID int64, toUserID int64) error
+	HandleDelayedJob(ctx context.Context, job model.DelayedJob) error
+	GetProducts(ctx context.Context) ([]model.Product, error)
+	Purchase(ctx context.Context, pr model.PurchaseRequest) (model.Order, error)
+	Gift(ctx context.Context, gr model.GiftRequest) (model.Order, error)
+	HandlePaymentNotification(ctx context.Context, payload []byte, signature string) error
 }
 
 type CoreService struct {
-	Repo      repository.ICoreRepository
-	RedisRepo repository.IRedisCoreRepository
-	Cfg       *config.Config
+	Repo        repository.ICoreRepository
+	RedisRepo   repository.IRedisCoreRepository
+	MessageRepo repository.IMessageRepository
+	EventRepo   repository.IEventRepository
+	JobRepo     repository.IJobRepository
+	ProductRepo repository.IProductRepository
+	OrderRepo   repository.IOrderRepository
+	AuditRepo   repository.IAuditRepository
+	TokenRepo   repository.ITokenRepository
+
+	LoginAttemptRepo      repository.ILoginAttemptRepository
+	PasswordResetRepo     repository.IPasswordResetRepository
+	EmailVerificationRepo repository.IEmailVerificationRepository
+
+	UserClient     pb.UserServiceClient
+	AuthClient     pb.AuthServiceClient
+	PaymentGateway payment_gateway.IPaymentGateway
+	JWTVerifier    jwt_verifier.IJWTVerifier
+	Mailer         mailer.IMailer
+
+	Cfg *config.Config
 }
 
 func NewCoreService(
 	coreRepo repository.ICoreRepository,
 	redisRepo repository.IRedisCoreRepository,
+	messageRepo repository.IMessageRepository,
+	eventRepo repository.IEventRepository,
+	jobRepo repository.IJobRepository,
+	productRepo repository.IProductRepository,
+	orderRepo repository.IOrderRepository,
+	auditRepo repository.IAuditRepository,
+	tokenRepo repository.ITokenRepository,
+	loginAttemptRepo repository.ILoginAttemptRepository,
+	passwordResetRepo repository.IPasswordResetRepository,
+	emailVerificationRepo repository.IEmailVerificationRepository,
+	userClient pb.UserServiceClient,
+	authClient pb.AuthServiceClient,
+	paymentGateway payment_gateway.IPaymentGateway,
+	jwtVerifier jwt_verifier.IJWTVerifier,
+	mailer mailer.IMailer,
 	cfg *config.Config) *CoreService {
 
 	return &CoreService{
-		Repo:      coreRepo,
-		RedisRepo: redisRepo,
-		Cfg:       cfg,
+		Repo:        coreRepo,
+		RedisRepo:   redisRepo,
+		MessageRepo: messageRepo,
+		EventRepo:   eventRepo,
+		JobRepo:     jobRepo,
+		ProductRepo: productRepo,
+		OrderRepo:   orderRepo,
+		AuditRepo:   auditRepo,
+		TokenRepo:   tokenRepo,
+
+		LoginAttemptRepo:      loginAttemptRepo,
+		PasswordResetRepo:     passwordResetRepo,
+		EmailVerificationRepo: emailVerificationRepo,
+
+		UserClient:     userClient,
+		AuthClient:     authClient,
+		PaymentGateway: paymentGateway,
+		JWTVerifier:    jwtVerifier,
+		Mailer:         mailer,
+
+		Cfg: cfg,
 	}
 }
 
 func (cs *CoreService) SignUp(ctx context.Context, signUpRequest model.SignUpRequest) error {
-	conn, err := GetUserServiceConnection(cs.Cfg.UserServerConfig.Host, cs.Cfg.UserServerConfig.Port)
+	r, err := cs.UserClient.IsUserExist(ctx, &pb.IsUserExistRequest{Email: signUpRequest.Email})
 	if err != nil {
-		log.Printf("did not connect: %v", err)
-		return errors.New(util.ErrInternalError)
-	}
-	defer conn.Close()
-	c := pb.NewUserServiceClient(conn)
-
-	gCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	r, err := c.IsUserExist(gCtx, &pb.IsUserExistRequest{Email: signUpRequest.Email})
-	if err != nil {
-		log.Printf("call IsUserExist failed: %v", err)
-		return errors.New(util.ErrInternalError)
+		return downstreamError("IsUserExist", err)
 	}
 	log.Printf("IsUserExist: %v", r.IsUserExist)
 
 	if r.IsUserExist {
-		return errors.New("user already exists")
+		return apperror.ErrUserAlreadyExists
 	}
 
-	gCtx, cancel = context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+	// the account is marked unverified before it exists so it can't be used unverified in between
+	verificationToken, err := cs.startEmailVerification(ctx, signUpRequest.Email)
+	if err != nil {
+		return err
+	}
 
-	rUser, err := c.CreateUser(gCtx, &pb.CreateUserRequest{
+	rUser, err := cs.UserClient.CreateUser(ctx, &pb.CreateUserRequest{
 		User: &pb.User{
 			FullName: signUpRequest.Fullname,
 			Gender:   signUpRequest.Gender,
@@ -81,179 +150,606 @@ func (cs *CoreService) SignUp(ctx context.Context, signUpRequest model.SignUpReq
 		},
 	})
 	if err != nil {
-		log.Printf("call CreateUser failed: %v", err)
-		return errors.New(util.ErrInternalError)
+		return downstreamError("CreateUser", err)
 	}
 	log.Printf("CreateUser: %v", rUser.Message)
 
+	if signUpRequest.Timezone != "" {
+		err = cs.RedisRepo.StoreTimezone(ctx, signUpRequest.Email, signUpRequest.Timezone)
+		if err != nil {
+			log.Printf("store timezone of %s failed: %v", signUpRequest.Email, err)
+		}
+	}
+
+	cs.sendVerificationMail(signUpRequest.Email, verificationToken)
 	return nil
 }
 
-func (cs *CoreService) Login(ctx context.Context, loginRequest model.LoginRequest) (string, error) {
-	user, err := HandleGetUserByEmail(ctx, cs.Cfg, loginRequest)
+// downstreamError turns err returned by call into the error returned to the client, failures of the service
+// itself are logged
+func downstreamError(call string, err error) *apperror.Error {
+	appErr := apperror.FromGRPC(err)
+	if appErr.Status >= http.StatusInternalServerError {
+		log.Printf("call %s failed: %v", call, err)
+	}
+	return appErr
+}
+
+// userLookupError is downstreamError for calls looking up a user, a missing user is reported as ErrUserNotFound
+// whether user service answers NotFound or an empty user, so callers hiding unknown accounts only check one error
+func userLookupError(call string, err error) *apperror.Error {
+	appErr := downstreamError(call, err)
+	if errors.Is(appErr, apperror.ErrNotFound) {
+		return apperror.ErrUserNotFound.Wrap(err)
+	}
+	return appErr
+}
+
+// Login exchanges email and password for a token pair. Unknown emails and wrong passwords get the same error
+// and take the same time, failures are counted per email and IP and lock them out once over the threshold.
+func (cs *CoreService) Login(ctx context.Context, loginRequest model.LoginRequest) (model.TokenPair, error) {
+	err := cs.checkLoginLockout(ctx, loginRequest)
 	if err != nil {
-		return "", errors.New("invalid email or password 1")
+		return model.TokenPair{}, err
+	}
+
+	storedHashedPassword := util.DummyPasswordHash
+	user, err := HandleGetUserByEmail(ctx, cs.UserClient, loginRequest)
+	if err != nil && !errors.Is(err, apperror.ErrUserNotFound) {
+		return model.TokenPair{}, err
 	}
+	if err == nil {
+		storedHashedPassword = user.User.Password
+	}
+
+	passwordErr := util.ValidatePassword(loginRequest.Password, storedHashedPassword)
+	if err != nil || passwordErr != nil {
+		cs.recordLoginFailure(ctx, loginRequest)
+		return model.TokenPair{}, apperror.ErrInvalidCredentials
+	}
+	cs.resetLoginFailures(ctx, loginRequest)
 
-	err = util.ValidatePassword(loginRequest.Password, user.User.Password)
+	err = cs.checkEmailVerified(ctx, loginRequest.Email, cs.Cfg.EmailVerificationConfig.RestrictLogin)
 	if err != nil {
-		return "", errors.New("invalid email or password 2")
+		return model.TokenPair{}, err
 	}
 
-	rToken, err := HandleGetToken(ctx, cs.Cfg, loginRequest)
+	rToken, err := HandleGetToken(ctx, cs.AuthClient, loginRequest)
 	if err != nil {
-		return "", errors.New("invalid email or password 3")
+		return model.TokenPair{}, err
 	}
 
-	return rToken.Token, nil
+	return cs.issueTokenPair(ctx, loginRequest.Email, rToken.Token)
 }
 
-func (cs *CoreService) ViewProfile(ctx context.Context, vpRequest model.ViewProfileRequest) (model.Profile, error) {
-	var nextProfile model.Profile
-	rToken, err := HandleIsTokenValid(ctx, cs.Cfg, vpRequest.Token)
+func (cs *CoreService) ViewProfile(ctx context.Context, vpRequest model.ViewProfileRequest) (model.ViewProfileResult, error) {
+	var result model.ViewProfileResult
+	principal := vpRequest.Principal
+	err := cs.checkEmailVerified(ctx, principal.Email, cs.Cfg.EmailVerificationConfig.RestrictViewProfile)
 	if err != nil {
-		return nextProfile, err
+		return result, err
 	}
 
-	if rToken.Email == "" {
-		return nextProfile, errors.New(util.ErrInvalidToken)
+	viewProfileData, err := cs.getViewProfileData(ctx, principal.Email)
+	if err != nil {
+		return result, err
 	}
 
-	viewProfileData, err := cs.RedisRepo.GetViewProfile(ctx, fmt.Sprintf(KeyViewProfile, rToken.Email))
+	swipeQuotaKey := fmt.Sprintf(KeySwipeQuota, principal.Email)
+	viewedProfileIDsKey := fmt.Sprintf(KeyViewedProfileIDs, principal.Email)
+	resetAt, err := cs.nextSwipeQuotaReset(ctx, principal.Email)
 	if err != nil {
-		return nextProfile, err
+		return result, err
 	}
 
-	var rUser *pb.GetUserSubscriptionResponse
-	if viewProfileData.Email == "" {
-		rUser, err = HandleGetUserSubscription(ctx, cs.Cfg, vpRequest, viewProfileData.Email)
+	if vpRequest.SwipeLeft {
+		// pass: reserve a swipe, then get next profile
+		_, err = cs.RedisRepo.ConsumeSwipe(ctx, swipeQuotaKey, viewedProfileIDsKey, cs.swipeLimit(viewProfileData), resetAt, 0)
 		if err != nil {
-			return nextProfile, errors.New(util.ErrInternalError)
+			return result, err
 		}
 
-		for i := 0; i < len(rUser.Subscriptions); i++ {
-			if rUser.Subscriptions[i].ProductCode == util.UnlimitedSwipeProductCode {
-				viewProfileData.IsUnlimitedSwipe = true
+		rNextProfile, err := cs.getNextProfile(ctx, principal, viewedProfileIDsKey)
+		if err != nil {
+			cs.releaseSwipe(ctx, swipeQuotaKey)
+			return result, err
+		}
 
-				// TODO: Handle for subscription expired_at less than 24 hour
-				// - add delayed job for worker to update value IsUnlimitedSwipe to false
+		err = cs.RedisRepo.AddViewedProfileID(ctx, viewedProfileIDsKey, rNextProfile.User.Id, util.TimeNow(), cs.Cfg.HistoryConfig.Retention)
+		if err != nil {
+			cs.releaseSwipe(ctx, swipeQuotaKey)
+			return result, apperror.ErrInternal
+		}
 
-				break
-			}
+		result.NextProfile = cs.toProfile(ctx, rNextProfile.User, rNextProfile.Subscriptions)
+		err = cs.RedisRepo.StoreProfile(ctx, result.NextProfile)
+		if err != nil {
+			cs.releaseSwipe(ctx, swipeQuotaKey)
+			return result, apperror.ErrInternal
+		}
+	} else {
+		// like: only a profile that has been served can be liked
+		_, err = cs.RedisRepo.ConsumeSwipe(ctx, swipeQuotaKey, viewedProfileIDsKey, cs.swipeLimit(viewProfileData), resetAt, vpRequest.CurrentViewedProfileID)
+		if err != nil {
+			return result, err
 		}
 
-		viewProfileData.ViewerID = rUser.User.Id
-		viewProfileData.Email = rToken.Email
-		viewProfileData.ViewedProfileIDs = make([]int64, 0)
-		viewProfileData.ViewerGender = rUser.User.Gender
-		err = cs.RedisRepo.StoreViewProfile(ctx, fmt.Sprintf(KeyViewProfile, rToken.Email), viewProfileData)
+		result.Match, err = cs.like(ctx, principal.UserID, vpRequest.CurrentViewedProfileID)
 		if err != nil {
-			return nextProfile, errors.New(util.ErrInternalError)
+			cs.releaseSwipe(ctx, swipeQuotaKey)
+			return result, err
 		}
 	}
 
-	// handle swipe count
-	if viewProfileData.SwipeCount >= 10 && !viewProfileData.IsUnlimitedSwipe {
-		return nextProfile, errors.New("already used up all swipe quota")
+	return result, nil
+}
+
+func (cs *CoreService) GetQuota(ctx context.Context, qr model.QuotaRequest) (model.QuotaStatus, error) {
+	var quotaStatus model.QuotaStatus
+	principal := qr.Principal
+	viewProfileData, err := cs.getViewProfileData(ctx, principal.Email)
+	if err != nil {
+		return quotaStatus, err
 	}
 
-	if vpRequest.SwipeLeft {
-		// pass: get next profile
-		excludeIDs := viewProfileData.ViewedProfileIDs
-		log.Println(viewProfileData.ViewedProfileIDs)
-		nextProfileGender := "F"
-		if viewProfileData.ViewerGender == "F" {
-			nextProfileGender = "M"
+	swipeQuota, err := cs.getSwipeQuota(ctx, principal.Email)
+	if err != nil {
+		return quotaStatus, err
+	}
+
+	quotaStatus.IsUnlimited = viewProfileData.IsUnlimitedSwipe
+	quotaStatus.Limit = cs.Cfg.QuotaConfig.DailySwipeLimit
+	quotaStatus.Remaining = quotaStatus.Limit - swipeQuota.SwipeCount
+	if quotaStatus.Remaining < 0 {
+		quotaStatus.Remaining = 0
+	}
+	quotaStatus.ResetAt = swipeQuota.ResetAt
+	return quotaStatus, nil
+}
+
+// getNextProfile fetches a profile the viewer hasn't seen yet. At most max-exclude-ids ids are sent as exclusion
+// so the request stays bounded: the viewer, unmatched users, then the latest viewed history. A candidate left out
+// of the exclusion that was viewed or unmatched before is skipped and fetched again.
+func (cs *CoreService) getNextProfile(ctx context.Context, principal model.Principal, viewedProfileIDsKey string) (*pb.GetNextProfileExceptIDsResponse, error) {
+	maxExcludeIDs := cs.Cfg.HistoryConfig.MaxExcludeIDs
+	unmatchedIDs, err := cs.RedisRepo.GetUnmatchedIDs(ctx, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	viewedIDs, err := cs.RedisRepo.GetViewedProfileIDs(ctx, viewedProfileIDsKey, maxExcludeIDs)
+	if err != nil {
+		return nil, err
+	}
+	nextProfileGender := "F"
+	if principal.Gender == "F" {
+		nextProfileGender = "M"
+	}
+
+	excludeIDs := append([]int64{principal.UserID}, unmatchedIDs...)
+	excludeIDs = append(excludeIDs, viewedIDs...)
+	if int64(len(excludeIDs)) > maxExcludeIDs {
+		excludeIDs = excludeIDs[:maxExcludeIDs]
+	}
+	isUnmatched := make(map[int64]bool, len(unmatchedIDs))
+	for _, id := range unmatchedIDs {
+		isUnmatched[id] = true
+	}
+
+	for i := 0; i < util.MaxNextProfileAttempts; i++ {
+		rNextProfile, err := HandleGetNextProfileExceptIDs(ctx, cs.UserClient, excludeIDs, nextProfileGender)
+		if err != nil {
+			return nil, err
 		}
-		excludeIDs = append(excludeIDs, viewProfileData.ViewerID)
 
-		rNextProfile, err := HandleGetNextProfileExceptIDs(ctx, cs.Cfg, excludeIDs, nextProfileGender)
+		isViewed, err := cs.RedisRepo.IsViewedProfile(ctx, viewedProfileIDsKey, rNextProfile.User.Id)
 		if err != nil {
-			return nextProfile, err
+			return nil, err
 		}
+		if !isViewed && !isUnmatched[rNextProfile.User.Id] {
+			return rNextProfile, nil
+		}
+		excludeIDs = append(excludeIDs, rNextProfile.User.Id)
+	}
 
-		viewProfileData.ViewedProfileIDs = append(viewProfileData.ViewedProfileIDs, rNextProfile.User.Id)
-		viewProfileData.SwipeCount++
+	// every attempt hit the history or an unmatched user, reported like running out of profiles
+	return nil, apperror.ErrUserNotFound
+}
+
+// getSwipeQuota returns the current swipe quota usage, or an empty window when none has been opened yet
+func (cs *CoreService) getSwipeQuota(ctx context.Context, email string) (model.SwipeQuota, error) {
+	swipeQuota, err := cs.RedisRepo.GetSwipeQuota(ctx, fmt.Sprintf(KeySwipeQuota, email))
+	if err != nil {
+		return swipeQuota, err
+	}
 
-		err = cs.RedisRepo.StoreViewProfile(ctx, fmt.Sprintf(KeyViewProfile, rToken.Email), viewProfileData)
+	if swipeQuota.ResetAt.IsZero() {
+		swipeQuota.ResetAt, err = cs.nextSwipeQuotaReset(ctx, email)
 		if err != nil {
-			return nextProfile, errors.New(util.ErrInternalError)
+			return swipeQuota, err
 		}
+	}
 
-		nextProfile.ID = rNextProfile.User.Id
-		nextProfile.Fullname = rNextProfile.User.FullName
-		nextProfile.PhotoURL = rNextProfile.User.PhotoUrl
-		for i := 0; i < len(rNextProfile.Subscriptions); i++ {
-			if rNextProfile.Subscriptions[i].ProductCode == util.AccountVerifiedProductCode {
-				nextProfile.IsVerified = true
-				break
-			}
-		}
-	} else {
-		// like:
-		viewProfileData.SwipeCount++
-		err = cs.RedisRepo.StoreViewProfile(ctx, fmt.Sprintf(KeyViewProfile, rToken.Email), viewProfileData)
+	return swipeQuota, nil
+}
+
+// nextSwipeQuotaReset returns the next local midnight in the timezone the user signed up with, or
+// default-timezone. It is the reset time of a quota window opened now. The timezone is never taken from the
+// request, switching it to one whose midnight just passed would open a fresh window.
+func (cs *CoreService) nextSwipeQuotaReset(ctx context.Context, email string) (time.Time, error) {
+	timezone, err := cs.RedisRepo.GetTimezone(ctx, email)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if timezone == "" {
+		timezone = cs.Cfg.QuotaConfig.DefaultTimezone
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, apperror.ErrInvalidTimezone
+	}
+
+	return util.NextMidnight(util.TimeNow(), location), nil
+}
+
+// swipeLimit returns the daily swipe limit of the viewer, 0 means unlimited
+func (cs *CoreService) swipeLimit(viewProfileData model.ViewProfile) int64 {
+	if viewProfileData.IsUnlimitedSwipe {
+		return 0
+	}
+	return cs.Cfg.QuotaConfig.DailySwipeLimit
+}
+
+// releaseSwipe gives back a swipe reserved for a request that failed afterwards
+func (cs *CoreService) releaseSwipe(ctx context.Context, swipeQuotaKey string) {
+	err := cs.RedisRepo.ReleaseSwipe(ctx, swipeQuotaKey)
+	if err != nil {
+		log.Printf("release swipe %s failed: %v", swipeQuotaKey, err)
+	}
+}
+
+// getViewProfileData loads the viewer's session data, initializing it from user service on first use
+func (cs *CoreService) getViewProfileData(ctx context.Context, email string) (model.ViewProfile, error) {
+	viewProfileData, err := cs.RedisRepo.GetViewProfile(ctx, fmt.Sprintf(KeyViewProfile, email))
+	if err != nil {
+		return viewProfileData, err
+	}
+
+	// entitlements are checked against the expiry locally too, so they lapse even when the expire job is late
+	if viewProfileData.Email != "" && !viewProfileData.IsEntitlementExpired(util.TimeNow()) {
+		return viewProfileData, nil
+	}
+
+	rUser, err := HandleGetUserSubscription(ctx, cs.UserClient, email)
+	if err != nil {
+		return viewProfileData, err
+	}
+
+	viewProfileData.ViewerID = rUser.User.Id
+	viewProfileData.Email = email
+	viewProfileData.ViewerGender = rUser.User.Gender
+	cs.applyEntitlements(ctx, &viewProfileData, rUser.Subscriptions)
+	err = cs.RedisRepo.StoreViewProfile(ctx, fmt.Sprintf(KeyViewProfile, email), viewProfileData)
+	if err != nil {
+		return viewProfileData, apperror.ErrInternal
+	}
+
+	// keep viewer's own profile so it can be shown to the other side of a match
+	err = cs.RedisRepo.StoreProfile(ctx, cs.toProfile(ctx, rUser.User, rUser.Subscriptions))
+	if err != nil {
+		return viewProfileData, apperror.ErrInternal
+	}
+
+	return viewProfileData, nil
+}
+
+// like records viewerID liking targetID and creates a match when targetID already liked viewerID
+func (cs *CoreService) like(ctx context.Context, viewerID int64, targetID int64) (*model.Match, error) {
+	// an unmatched pair never matches again, the like is dropped without telling the viewer
+	isUnmatched, err := cs.RedisRepo.IsUnmatched(ctx, viewerID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if isUnmatched {
+		return nil, nil
+	}
+
+	now := util.TimeNow()
+	err = cs.RedisRepo.StoreLike(ctx, viewerID, targetID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	isLikedBack, err := cs.RedisRepo.IsLiked(ctx, targetID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLikedBack {
+		return nil, nil
+	}
+
+	err = cs.RedisRepo.StoreMatch(ctx, viewerID, targetID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	matchedProfile, err := cs.RedisRepo.GetProfile(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	viewerProfile, err := cs.RedisRepo.GetProfile(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	match := &model.Match{
+		Profile:   matchedProfile,
+		MatchedAt: now,
+	}
+	cs.publishEvent(ctx, targetID, model.Event{
+		Type:       util.EventTypeMatch,
+		FromUserID: viewerID,
+		Match:      &model.Match{Profile: viewerProfile, MatchedAt: now},
+	})
+	cs.publishEvent(ctx, viewerID, model.Event{
+		Type:       util.EventTypeMatch,
+		FromUserID: targetID,
+		Match:      match,
+	})
+
+	return match, nil
+}
+
+func (cs *CoreService) GetMatches(ctx context.Context, mr model.MatchesRequest) ([]model.Match, int64, error) {
+	principal := mr.Principal
+	matches, total, err := cs.RedisRepo.GetMatches(ctx, principal.UserID, (mr.Page-1)*mr.PageSize, mr.PageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := 0; i < len(matches); i++ {
+		matches[i].Profile, err = cs.RedisRepo.GetProfile(ctx, matches[i].ID)
 		if err != nil {
-			return nextProfile, errors.New(util.ErrInternalError)
+			return nil, 0, err
 		}
 	}
 
-	return nextProfile, nil
+	return matches, total, nil
 }
 
-func (cs *CoreService) Purchase(ctx context.Context, pr model.PurchaseRequest) error {
-	rToken, err := HandleIsTokenValid(ctx, cs.Cfg, pr.Token)
+func (cs *CoreService) Unmatch(ctx context.Context, ur model.UnmatchRequest) error {
+	principal := ur.Principal
+	isMatched, err := cs.RedisRepo.IsMatched(ctx, principal.UserID, ur.MatchedUserID)
 	if err != nil {
 		return err
 	}
+	if !isMatched {
+		return apperror.ErrMatchNotFound
+	}
+
+	return cs.RedisRepo.DeleteMatch(ctx, principal.UserID, ur.MatchedUserID)
+}
+
+func (cs *CoreService) GetProducts(ctx context.Context) ([]model.Product, error) {
+	return cs.ProductRepo.GetProducts(ctx)
+}
+
+// Purchase creates a pending order for the caller and a payment intent at the payment gateway.
+// The subscription is granted once the gateway reports the payment through HandlePaymentNotification.
+func (cs *CoreService) Purchase(ctx context.Context, pr model.PurchaseRequest) (model.Order, error) {
+	principal := pr.Principal
+	// user_id is optional, when sent it must be the caller, buying for someone else goes through Gift
+	if pr.UserID != 0 && pr.UserID != principal.UserID {
+		return model.Order{}, apperror.ErrForbidden
+	}
 
-	if rToken.Email == "" {
-		return errors.New(util.ErrInvalidToken)
+	return cs.createOrder(ctx, model.Order{
+		UserID:      principal.UserID,
+		Email:       principal.Email,
+		ProductCode: pr.ProductCode,
+	})
+}
+
+// Gift creates a pending order paid by the caller that grants the product to another user.
+// Gift orders are recorded in the audit log when created and when fulfilled.
+func (cs *CoreService) Gift(ctx context.Context, gr model.GiftRequest) (model.Order, error) {
+	principal := gr.Principal
+	if gr.RecipientEmail == principal.Email {
+		return model.Order{}, apperror.ErrGiftToSelf
+	}
+
+	rRecipient, err := HandleGetUserSubscription(ctx, cs.UserClient, gr.RecipientEmail)
+	if err != nil && !errors.Is(err, apperror.ErrUserNotFound) {
+		return model.Order{}, err
+	}
+	if err != nil || rRecipient.GetUser().GetId() == 0 {
+		return model.Order{}, apperror.ErrRecipientNotFound
 	}
 
-	_, err = HandleUpsertSubscription(ctx, cs.Cfg, pr, rToken.Email)
+	order, err := cs.createOrder(ctx, model.Order{
+		UserID:       rRecipient.GetUser().GetId(),
+		Email:        gr.RecipientEmail,
+		ProductCode:  gr.ProductCode,
+		GifterUserID: principal.UserID,
+		GifterEmail:  principal.Email,
+	})
+	if err != nil {
+		return order, err
+	}
+
+	cs.audit(ctx, model.AuditEntry{
+		Action:      util.AuditActionGiftOrderCreated,
+		ActorID:     principal.UserID,
+		ActorEmail:  principal.Email,
+		TargetID:    order.UserID,
+		TargetEmail: order.Email,
+		OrderID:     order.ID,
+		ProductCode: order.ProductCode,
+	})
+	return order, nil
+}
+
+// createOrder prices order from the catalog, opens a payment intent and stores it as pending
+func (cs *CoreService) createOrder(ctx context.Context, order model.Order) (model.Order, error) {
+	product, err := cs.ProductRepo.GetProduct(ctx, order.ProductCode)
+	if err != nil {
+		return order, err
+	}
+
+	now := util.TimeNow()
+	order.ID = util.RandomID(16)
+	order.Amount = product.Price
+	order.Currency = product.Currency
+	order.Status = util.OrderStatusPending
+	order.CreatedAt = now
+	order.UpdatedAt = now
+
+	paymentIntent, err := cs.PaymentGateway.CreatePaymentIntent(ctx, order)
+	if err != nil {
+		return order, err
+	}
+	order.PaymentID = paymentIntent.ID
+	order.PaymentURL = paymentIntent.PaymentURL
+
+	err = cs.OrderRepo.StoreOrder(ctx, order)
+	if err != nil {
+		return order, err
+	}
+
+	return order, nil
+}
+
+// HandlePaymentNotification verifies a payment gateway callback and fulfills the order once it is paid.
+// Duplicate callbacks of a settled order are acknowledged without granting the subscription again.
+func (cs *CoreService) HandlePaymentNotification(ctx context.Context, payload []byte, signature string) error {
+	notification, err := cs.PaymentGateway.ParseNotification(payload, signature)
 	if err != nil {
 		return err
 	}
 
-	viewProfileData, _ := cs.RedisRepo.GetViewProfile(ctx, fmt.Sprintf(KeyViewProfile, rToken.Email))
-	if viewProfileData.Email == rToken.Email {
-		if pr.ProductCode == util.UnlimitedSwipeProductCode {
-			viewProfileData.IsUnlimitedSwipe = true
-			cs.RedisRepo.StoreViewProfile(ctx, fmt.Sprintf(KeyViewProfile, rToken.Email), viewProfileData)
+	order, err := cs.OrderRepo.GetOrder(ctx, notification.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.PaymentID != notification.PaymentID {
+		return apperror.ErrOrderNotFound
+	}
+
+	switch notification.Status {
+	case util.OrderStatusPaid:
+	case util.OrderStatusFailed:
+		_, err = cs.OrderRepo.TransitionOrderStatus(ctx, order.ID, util.OrderStatusPending, util.OrderStatusFailed, util.TimeNow())
+		if err != nil && errors.Is(err, apperror.ErrOrderStatusConflict) {
+			return nil
 		}
+		return err
+	default:
+		return nil
 	}
 
-	return nil
+	// scheduled first so an order can't be left processing by a crash without anything picking it up again
+	err = cs.scheduleOrderRecovery(ctx, model.DelayedJob{Email: order.Email, OrderID: order.ID}, util.TimeNow())
+	if err != nil {
+		return err
+	}
+	order, err = cs.OrderRepo.TransitionOrderStatus(ctx, order.ID, util.OrderStatusPending, util.OrderStatusProcessing, util.TimeNow())
+	if err != nil {
+		if errors.Is(err, apperror.ErrOrderStatusConflict) {
+			return nil
+		}
+		return err
+	}
+
+	err = cs.fulfillOrder(ctx, order)
+	if err != nil {
+		// put the order back so the gateway retry can fulfill it
+		cs.OrderRepo.TransitionOrderStatus(ctx, order.ID, util.OrderStatusProcessing, util.OrderStatusPending, util.TimeNow())
+		return err
+	}
+
+	_, err = cs.OrderRepo.TransitionOrderStatus(ctx, order.ID, util.OrderStatusProcessing, util.OrderStatusPaid, util.TimeNow())
+	return err
 }
 
-var HandleGetUserSubscription = func(
-	ctx context.Context,
-	cfg *config.Config,
-	viewProfileRequest model.ViewProfileRequest,
-	email string) (*pb.GetUserSubscriptionResponse, error) {
+// fulfillOrder grants the subscription of a paid order, fulfilling it again grants the same expiry
+func (cs *CoreService) fulfillOrder(ctx context.Context, order model.Order) error {
+	product, err := cs.ProductRepo.GetProduct(ctx, order.ProductCode)
+	if err != nil {
+		return err
+	}
 
-	conn, err := GetUserServiceConnection(cfg.UserServerConfig.Host, cfg.UserServerConfig.Port)
+	expiredAt := order.SubscriptionExpiredAt
+	if expiredAt.IsZero() {
+		rUser, err := HandleGetUserSubscription(ctx, cs.UserClient, order.Email)
+		if err != nil {
+			return apperror.ErrInternal
+		}
+
+		// renewing an active subscription extends it from its current expiry
+		now := util.TimeNow()
+		startAt := now
+		for i := 0; i < len(rUser.Subscriptions); i++ {
+			if rUser.Subscriptions[i].ProductCode != product.Code {
+				continue
+			}
+			if expiredAt, isActive := subscriptionExpiry(rUser.Subscriptions[i], now); isActive && expiredAt.After(startAt) {
+				startAt = expiredAt
+			}
+		}
+		expiredAt = startAt.Add(product.Duration)
+
+		order.SubscriptionExpiredAt = expiredAt
+		err = cs.OrderRepo.StoreOrder(ctx, order)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = HandleUpsertSubscription(ctx, cs.UserClient, model.PurchaseRequest{
+		UserID:      order.UserID,
+		ProductCode: product.Code,
+		ProductName: product.Name,
+		ExpiredAt:   expiredAt.UTC().Format(util.DateFormatYYYYMMDDTHHmmss),
+	}, order.Email)
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
-		return nil, errors.New(util.ErrInternalError)
+		return err
+	}
+
+	viewProfileData, _ := cs.RedisRepo.GetViewProfile(ctx, fmt.Sprintf(KeyViewProfile, order.Email))
+	if viewProfileData.Email == order.Email && grantEntitlements(&viewProfileData, product, expiredAt) {
+		cs.RedisRepo.StoreViewProfile(ctx, fmt.Sprintf(KeyViewProfile, order.Email), viewProfileData)
+		cs.scheduleSubscriptionExpiry(ctx, order.Email, product.Code, expiredAt)
+	}
+
+	if order.GifterUserID != 0 {
+		cs.audit(ctx, model.AuditEntry{
+			Action:      util.AuditActionGiftFulfilled,
+			ActorID:     order.GifterUserID,
+			ActorEmail:  order.GifterEmail,
+			TargetID:    order.UserID,
+			TargetEmail: order.Email,
+			OrderID:     order.ID,
+			ProductCode: order.ProductCode,
+		})
 	}
-	defer conn.Close()
-	c := pb.NewUserServiceClient(conn)
 
-	gCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
+	return nil
+}
+
+var HandleGetUserSubscription = func(
+	ctx context.Context,
+	c pb.UserServiceClient,
+	email string) (*pb.GetUserSubscriptionResponse, error) {
 
-	rUser, err := c.GetUserSubscription(gCtx, &pb.GetUserSubscriptionRequest{
+	rUser, err := c.GetUserSubscription(ctx, &pb.GetUserSubscriptionRequest{
 		Email: email,
 	})
 	if err != nil {
-		log.Printf("call GetUserSubscription failed: %v", err)
-		return nil, errors.New(util.ErrInternalError)
+		return nil, userLookupError("GetUserSubscription", err)
 	}
 
 	if rUser.User.Id == 0 {
-		return nil, errors.New("user not found")
+		return nil, apperror.ErrUserNotFound
 	}
 
 	return rUser, nil
@@ -261,36 +757,21 @@ var HandleGetUserSubscription = func(
 
 var HandleGetNextProfileExceptIDs = func(
 	ctx context.Context,
-	cfg *config.Config,
+	c pb.UserServiceClient,
 	ids []int64,
 	gender string) (*pb.GetNextProfileExceptIDsResponse, error) {
 
-	conn, err := GetUserServiceConnection(cfg.UserServerConfig.Host, cfg.UserServerConfig.Port)
-	if err != nil {
-		log.Fatalf("did not connect: %v", err)
-		return nil, errors.New(util.ErrInternalError)
-	}
-	defer conn.Close()
-	c := pb.NewUserServiceClient(conn)
-
-	gCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	rUser, err := c.GetNextProfileExceptIDs(gCtx, &pb.GetNextProfileExceptIDsRequest{
+	rUser, err := c.GetNextProfileExceptIDs(ctx, &pb.GetNextProfileExceptIDsRequest{
 		Ids:    ids,
 		Gender: gender,
 	})
 
 	if err != nil {
-		if status.Code(err) == 05 {
-			return nil, errors.New("user not found")
-		}
-		log.Printf("call GetNextProfileExceptIDs failed: %v", err)
-		return nil, errors.New(util.ErrInternalError)
+		return nil, userLookupError("GetNextProfileExceptIDs", err)
 	}
 
 	if rUser.User.Id == 0 {
-		return nil, errors.New("user not found")
+		return nil, apperror.ErrUserNotFound
 	}
 
 	return rUser, nil
@@ -298,28 +779,16 @@ var HandleGetNextProfileExceptIDs = func(
 
 var HandleGetUserByEmail = func(
 	ctx context.Context,
-	cfg *config.Config,
+	c pb.UserServiceClient,
 	loginRequest model.LoginRequest) (*pb.GetUserByEmailResponse, error) {
 
-	conn, err := GetUserServiceConnection(cfg.UserServerConfig.Host, cfg.UserServerConfig.Port)
+	rUser, err := c.GetUserByEmail(ctx, &pb.GetUserByEmailRequest{Email: loginRequest.Email})
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
-		return nil, errors.New(util.ErrInternalError)
-	}
-	defer conn.Close()
-	c := pb.NewUserServiceClient(conn)
-
-	gCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	rUser, err := c.GetUserByEmail(gCtx, &pb.GetUserByEmailRequest{Email: loginRequest.Email})
-	if err != nil {
-		log.Printf("call GetUserByEmail failed: %v", err)
-		return nil, errors.New(util.ErrInternalError)
+		return nil, userLookupError("GetUserByEmail", err)
 	}
 
 	if rUser.User.Id == 0 {
-		return nil, errors.New("user not found")
+		return nil, apperror.ErrUserNotFound
 	}
 
 	return rUser, nil
@@ -327,28 +796,16 @@ var HandleGetUserByEmail = func(
 
 var HandleGetToken = func(
 	ctx context.Context,
-	cfg *config.Config,
+	c pb.AuthServiceClient,
 	loginRequest model.LoginRequest) (*pb.GetTokenResponse, error) {
 
-	conn, err := GetAuthServiceConnection(cfg.AuthServerConfig.Host, cfg.AuthServerConfig.Port)
+	rToken, err := c.GetToken(ctx, &pb.GetTokenRequest{Email: loginRequest.Email})
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
-		return nil, errors.New(util.ErrInternalError)
-	}
-	defer conn.Close()
-	c := pb.NewAuthServiceClient(conn)
-
-	gCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	rToken, err := c.GetToken(gCtx, &pb.GetTokenRequest{Email: loginRequest.Email})
-	if err != nil {
-		log.Printf("call GetToken failed: %v", err)
-		return nil, errors.New(util.ErrInternalError)
+		return nil, downstreamError("GetToken", err)
 	}
 
 	if rToken.Token == "" {
-		return nil, errors.New(util.ErrInternalError)
+		return nil, apperror.ErrInternal
 	}
 
 	return rToken, nil
@@ -356,31 +813,16 @@ var HandleGetToken = func(
 
 var HandleIsTokenValid = func(
 	ctx context.Context,
-	cfg *config.Config,
+	c pb.AuthServiceClient,
 	token string) (*pb.IsTokenValidResponse, error) {
 
-	conn, err := GetAuthServiceConnection(cfg.AuthServerConfig.Host, cfg.AuthServerConfig.Port)
+	rToken, err := c.IsTokenValid(ctx, &pb.IsTokenValidRequest{Token: token})
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
-		return nil, errors.New(util.ErrInternalError)
-	}
-	defer conn.Close()
-	c := pb.NewAuthServiceClient(conn)
-
-	gCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	rToken, err := c.IsTokenValid(gCtx, &pb.IsTokenValidRequest{Token: token})
-	if err != nil {
-		if status.Code(err) == util.CodeInvalidToken {
-			return nil, errors.New(util.ErrUnauthorized)
-		}
-		log.Printf("call IsTokenValid failed: %v", err)
-		return nil, errors.New(util.ErrInternalError)
+		return nil, downstreamError("IsTokenValid", err)
 	}
 
 	if !rToken.IsTokenValid {
-		return nil, errors.New(util.ErrUnauthorized)
+		return nil, apperror.ErrUnauthorized
 	}
 
 	return rToken, nil
@@ -388,22 +830,11 @@ var HandleIsTokenValid = func(
 
 var HandleUpsertSubscription = func(
 	ctx context.Context,
-	cfg *config.Config,
+	c pb.UserServiceClient,
 	purchaseRequest model.PurchaseRequest,
 	email string) (*pb.UpsertSubscriptionResponse, error) {
 
-	conn, err := GetUserServiceConnection(cfg.UserServerConfig.Host, cfg.UserServerConfig.Port)
-	if err != nil {
-		log.Fatalf("did not connect: %v", err)
-		return nil, errors.New(util.ErrInternalError)
-	}
-	defer conn.Close()
-	c := pb.NewUserServiceClient(conn)
-
-	gCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
-	defer cancel()
-
-	rUpsertSubscription, err := c.UpsertSubscription(gCtx, &pb.UpsertSubscriptionRequest{
+	rUpsertSubscription, err := c.UpsertSubscription(ctx, &pb.UpsertSubscriptionRequest{
 		UserId:      purchaseRequest.UserID,
 		Email:       email,
 		ProductCode: purchaseRequest.ProductCode,
@@ -411,24 +842,38 @@ var HandleUpsertSubscription = func(
 		ExpiredAt:   purchaseRequest.ExpiredAt,
 	})
 	if err != nil {
-		if status.Code(err) == 14 {
-			log.Printf("call UpsertSubscription failed: %v", err)
-			return nil, errors.New(util.ErrInternalError)
+		appErr := downstreamError("UpsertSubscription", err)
+		if appErr.Status >= http.StatusInternalServerError {
+			return nil, appErr
 		}
-		return nil, errors.New(util.ErrProductNotFound)
+		return nil, apperror.ErrProductNotFound
 	}
 
 	return rUpsertSubscription, nil
 }
 
-var GetUserServiceConnection = func(host string, port int) (*grpc.ClientConn, error) {
-	return grpc.NewClient(
-		fmt.Sprintf("%v:%v", host, port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+var HandleUpdatePassword = func(
+	ctx context.Context,
+	c pb.UserServiceClient,
+	email string,
+	password string) (*pb.UpdatePasswordResponse, error) {
+
+	rUpdatePassword, err := c.UpdatePassword(ctx, &pb.UpdatePasswordRequest{
+		Email:    email,
+		Password: password,
+	})
+	if err != nil {
+		return nil, downstreamError("UpdatePassword", err)
+	}
+
+	return rUpdatePassword, nil
 }
 
-var GetAuthServiceConnection = func(host string, port int) (*grpc.ClientConn, error) {
-	return grpc.NewClient(
-		fmt.Sprintf("%v:%v", host, port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+func (cs *CoreService) toProfile(ctx context.Context, user *pb.User, subscriptions []*pb.UserSubscription) model.Profile {
+	return model.Profile{
+		ID:         user.Id,
+		Fullname:   user.FullName,
+		PhotoURL:   user.PhotoUrl,
+		IsVerified: cs.hasEntitlement(ctx, subscriptions, util.EntitlementVerified),
+	}
 }
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/repository"
+	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/atrariksa/kenalan-core/config"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/atrariksa/kenalan-core/app/external/grpc_client"
+)
+
+// fakeUserClient knows users and their subscriptions by email and serves candidates picked by next
+type fakeUserClient struct {
+	pb.UserServiceClient
+	users         map[string]*pb.User
+	subscriptions map[string][]*pb.UserSubscription
+	next          func(excludeIDs []int64) int64
+}
+
+func (f *fakeUserClient) GetUserSubscription(ctx context.Context, in *pb.GetUserSubscriptionRequest, opts ...grpc.CallOption) (*pb.GetUserSubscriptionResponse, error) {
+	user, ok := f.users[in.Email]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &pb.GetUserSubscriptionResponse{User: user, Subscriptions: f.subscriptions[in.Email]}, nil
+}
+
+func (f *fakeUserClient) GetNextProfileExceptIDs(ctx context.Context, in *pb.GetNextProfileExceptIDsRequest, opts ...grpc.CallOption) (*pb.GetNextProfileExceptIDsResponse, error) {
+	id := f.next(in.Ids)
+	if id == 0 {
+		return nil, status.Error(codes.NotFound, "no more profiles")
+	}
+	return &pb.GetNextProfileExceptIDsResponse{User: &pb.User{Id: id, Gender: in.Gender}}, nil
+}
+
+func newTestCoreService(t *testing.T, userClient pb.UserServiceClient) *CoreService {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+
+	cfg := &config.Config{
+		QuotaConfig:   config.QuotaConfig{DailySwipeLimit: 10, DefaultTimezone: "Asia/Jakarta"},
+		HistoryConfig: config.HistoryConfig{Retention: time.Hour, MaxExcludeIDs: 100},
+		Products: []config.ProductConfig{
+			{Code: "premium", Name: "Premium", Duration: 30 * 24 * time.Hour, Entitlements: []string{util.EntitlementSeeWhoLikedYou}},
+		},
+	}
+	return NewCoreService(nil, repository.NewRedisCoreRepository(rc), nil, repository.NewRedisEventRepository(rc),
+		repository.NewRedisJobRepository(rc), repository.NewConfigProductRepository(cfg), nil, nil, nil, nil, nil, nil,
+		userClient, nil, nil, nil, nil, cfg)
+}
+
+func testPrincipal(id int64, gender string) model.Principal {
+	return model.Principal{UserID: id, Email: fmt.Sprintf("user%d@kenalan.local", id), Gender: gender}
+}
+
+func swipeCount(t *testing.T, cs *CoreService, principal model.Principal) int64 {
+	t.Helper()
+	quota, err := cs.RedisRepo.GetSwipeQuota(context.Background(), fmt.Sprintf(KeySwipeQuota, principal.Email))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return quota.SwipeCount
+}
+
+func TestViewProfileOnlyViewedCandidatesLeft(t *testing.T) {
+	viewer := testPrincipal(1, "M")
+	// the user service keeps serving the same profile, as when it fell off a truncated exclude list
+	userClient := &fakeUserClient{
+		users: map[string]*pb.User{viewer.Email: {Id: viewer.UserID, Gender: viewer.Gender}},
+		next:  func([]int64) int64 { return 7 },
+	}
+	cs := newTestCoreService(t, userClient)
+	ctx := context.Background()
+
+	result, err := cs.ViewProfile(ctx, model.ViewProfileRequest{Principal: viewer, SwipeLeft: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NextProfile.ID != 7 {
+		t.Fatalf("served profile %d, want 7", result.NextProfile.ID)
+	}
+
+	_, err = cs.ViewProfile(ctx, model.ViewProfileRequest{Principal: viewer, SwipeLeft: true})
+	if !errors.Is(err, apperror.ErrUserNotFound) {
+		t.Fatalf("got %v, want user not found", err)
+	}
+	if count := swipeCount(t, cs, viewer); count != 1 {
+		t.Fatalf("swipe count %d, want the failed swipe released", count)
+	}
+}
+
+func TestLikeAfterUnmatch(t *testing.T) {
+	alice := testPrincipal(1, "F")
+	bob := testPrincipal(2, "M")
+	premium := &pb.UserSubscription{
+		ProductCode: "premium",
+		IsActive:    true,
+		ExpiredAt:   util.TimeNow().Add(time.Hour).Format(util.DateFormatYYYYMMDDTHHmmss),
+	}
+	userClient := &fakeUserClient{
+		users: map[string]*pb.User{
+			alice.Email: {Id: alice.UserID, Gender: alice.Gender},
+			bob.Email:   {Id: bob.UserID, Gender: bob.Gender},
+		},
+		subscriptions: map[string][]*pb.UserSubscription{alice.Email: {premium}},
+		next: func(excludeIDs []int64) int64 {
+			if excludeIDs[0] == alice.UserID {
+				return bob.UserID
+			}
+			return alice.UserID
+		},
+	}
+	cs := newTestCoreService(t, userClient)
+	ctx := context.Background()
+
+	for _, principal := range []model.Principal{alice, bob} {
+		_, err := cs.ViewProfile(ctx, model.ViewProfileRequest{Principal: principal, SwipeLeft: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := cs.ViewProfile(ctx, model.ViewProfileRequest{Principal: bob, SwipeRight: true, CurrentViewedProfileID: alice.UserID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := cs.ViewProfile(ctx, model.ViewProfileRequest{Principal: alice, SwipeRight: true, CurrentViewedProfileID: bob.UserID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Match == nil {
+		t.Fatal("mutual likes did not match")
+	}
+	err = cs.Unmatch(ctx, model.UnmatchRequest{Principal: bob, MatchedUserID: alice.UserID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// bob likes alice again, still in his viewed history, and alice likes him back from her inbox
+	result, err = cs.ViewProfile(ctx, model.ViewProfileRequest{Principal: bob, SwipeRight: true, CurrentViewedProfileID: alice.UserID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Match != nil {
+		t.Fatal("unmatched pair matched again")
+	}
+
+	likes, total, err := cs.GetLikes(ctx, model.LikesRequest{Principal: alice, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(likes) != 0 || total != 0 {
+		t.Fatalf("got %d likes of %d, want none from the unmatched user", len(likes), total)
+	}
+	match, err := cs.LikeBack(ctx, model.LikeBackRequest{Principal: alice, LikerUserID: bob.UserID})
+	if !errors.Is(err, apperror.ErrLikeNotFound) || match != nil {
+		t.Fatalf("got match %v and error %v, want like not found", match, err)
+	}
+
+	// a like of the unmatched pair already in the inbox, as left by earlier releases, is neither listed nor liked back
+	err = cs.RedisRepo.StoreLike(ctx, bob.UserID, alice.UserID, util.TimeNow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	likes, total, err = cs.GetLikes(ctx, model.LikesRequest{Principal: alice, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(likes) != 0 || total != 0 {
+		t.Fatalf("got %d likes of %d, want none from the unmatched user", len(likes), total)
+	}
+	match, err = cs.LikeBack(ctx, model.LikeBackRequest{Principal: alice, LikerUserID: bob.UserID})
+	if !errors.Is(err, apperror.ErrLikeNotFound) || match != nil {
+		t.Fatalf("got match %v and error %v, want like not found", match, err)
+	}
+	isMatched, err := cs.RedisRepo.IsMatched(ctx, alice.UserID, bob.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isMatched {
+		t.Fatal("unmatched pair matched again")
+	}
+}
+
+// failingLikeRepository fails to store likes
+type failingLikeRepository struct {
+	repository.IRedisCoreRepository
+}
+
+func (r failingLikeRepository) StoreLike(ctx context.Context, viewerID int64, targetID int64, likedAt time.Time) error {
+	return apperror.ErrInternal
+}
+
+func TestViewProfileFailedLikeReleasesSwipe(t *testing.T) {
+	viewer := testPrincipal(1, "M")
+	userClient := &fakeUserClient{
+		users: map[string]*pb.User{viewer.Email: {Id: viewer.UserID, Gender: viewer.Gender}},
+		next:  func([]int64) int64 { return 7 },
+	}
+	cs := newTestCoreService(t, userClient)
+	ctx := context.Background()
+
+	_, err := cs.ViewProfile(ctx, model.ViewProfileRequest{Principal: viewer, SwipeLeft: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cs.RedisRepo = failingLikeRepository{cs.RedisRepo}
+	_, err = cs.ViewProfile(ctx, model.ViewProfileRequest{Principal: viewer, SwipeRight: true, CurrentViewedProfileID: 7})
+	if !errors.Is(err, apperror.ErrInternal) {
+		t.Fatalf("got %v, want internal error", err)
+	}
+	if count := swipeCount(t, cs, viewer); count != 1 {
+		t.Fatalf("swipe count %d, want the failed like released", count)
+	}
+}
+
+func TestEntitlementLapsesWithoutExpireJob(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	util.TimeNow = func() time.Time { return now }
+	t.Cleanup(func() { util.TimeNow = time.Now })
+
+	viewer := testPrincipal(1, "F")
+	premium := &pb.UserSubscription{
+		ProductCode: "premium",
+		IsActive:    true,
+		ExpiredAt:   now.Add(time.Hour).Format(util.DateFormatYYYYMMDDTHHmmss),
+	}
+	userClient := &fakeUserClient{
+		users:         map[string]*pb.User{viewer.Email: {Id: viewer.UserID, Gender: viewer.Gender}},
+		subscriptions: map[string][]*pb.UserSubscription{viewer.Email: {premium}},
+	}
+	cs := newTestCoreService(t, userClient)
+	ctx := context.Background()
+
+	err := cs.RedisRepo.StoreLike(ctx, 2, viewer.UserID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	likes, _, err := cs.GetLikes(ctx, model.LikesRequest{Principal: viewer, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(likes) != 1 || likes[0].IsRedacted {
+		t.Fatalf("got likes %+v, want the like shown while subscribed", likes)
+	}
+
+	// the subscription ends and the user service reports it inactive, while the expire job never runs
+	now = now.Add(time.Hour)
+	premium.IsActive = false
+	likes, _, err = cs.GetLikes(ctx, model.LikesRequest{Principal: viewer, Page: 1, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(likes) != 1 || !likes[0].IsRedacted {
+		t.Fatalf("got likes %+v, want the like redacted once the subscription expired", likes)
+	}
+	_, err = cs.LikeBack(ctx, model.LikeBackRequest{Principal: viewer, LikerUserID: 2})
+	if !errors.Is(err, apperror.ErrSubscriptionRequired) {
+		t.Fatalf("got %v, want subscription required", err)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+)
+
+// VerifyEmail marks the account the token was mailed to as verified, the token can be used once
+func (cs *CoreService) VerifyEmail(ctx context.Context, ver model.VerifyEmailRequest) error {
+	email, err := cs.EmailVerificationRepo.ConsumeEmailVerificationToken(ctx, util.HashToken(ver.Token))
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return apperror.ErrInvalidVerificationToken
+	}
+	return nil
+}
+
+// ResendVerificationEmail mails a new verification token when email belongs to an unverified account. Like
+// ForgotPassword it gives the same answer whatever the state of the account.
+func (cs *CoreService) ResendVerificationEmail(ctx context.Context, rver model.ResendVerificationEmailRequest) error {
+	isUnverified, err := cs.EmailVerificationRepo.IsEmailUnverified(ctx, rver.Email)
+	if err != nil {
+		return err
+	}
+	if !isUnverified {
+		return nil
+	}
+
+	isAcquired, err := cs.EmailVerificationRepo.AcquireEmailVerificationCooldown(ctx, rver.Email, cs.Cfg.EmailVerificationConfig.Cooldown)
+	if err != nil {
+		return err
+	}
+	if !isAcquired {
+		return nil
+	}
+
+	token, err := cs.startEmailVerification(ctx, rver.Email)
+	if err != nil {
+		return err
+	}
+	cs.sendVerificationMail(rver.Email, token)
+	return nil
+}
+
+// startEmailVerification marks email unverified and returns the token that verifies it
+func (cs *CoreService) startEmailVerification(ctx context.Context, email string) (string, error) {
+	token := util.RandomID(32)
+	err := cs.EmailVerificationRepo.StoreEmailVerificationToken(ctx, email, util.HashToken(token), cs.Cfg.EmailVerificationConfig.TokenTTL)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (cs *CoreService) sendVerificationMail(email string, token string) {
+	cs.sendMail(model.Mail{
+		To:      email,
+		Subject: "Verify your Kenalan email",
+		Body: fmt.Sprintf("Welcome to Kenalan!\n\n"+
+			"Open the link below within %d hours to verify your email:\n%s\n\n"+
+			"If you didn't sign up, ignore this mail.",
+			int(cs.Cfg.EmailVerificationConfig.TokenTTL.Hours()), fmt.Sprintf(cs.Cfg.EmailVerificationConfig.URL, token)),
+	})
+}
+
+// checkEmailVerified rejects unverified accounts when restrict is set
+func (cs *CoreService) checkEmailVerified(ctx context.Context, email string, restrict bool) error {
+	if !restrict {
+		return nil
+	}
+
+	isUnverified, err := cs.EmailVerificationRepo.IsEmailUnverified(ctx, email)
+	if err != nil {
+		return err
+	}
+	if isUnverified {
+		return apperror.ErrEmailNotVerified
+	}
+	return nil
+}
+
+// sendMail sends mail in the background, a slow or failing mail server must not hold up the request
+func (cs *CoreService) sendMail(mail model.Mail) {
+	go func() {
+		err := cs.Mailer.Send(context.Background(), mail)
+		if err != nil {
+			log.Printf("send mail %q failed: %v", mail.Subject, err)
+		}
+	}()
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+)
+
+// SubscribeEvents subscribes to real-time events of principal
+func (cs *CoreService) SubscribeEvents(ctx context.Context, principal model.Principal) (model.EventSubscription, error) {
+	return cs.EventRepo.SubscribeEvents(ctx, principal.UserID)
+}
+
+// SendTyping notifies toUserID that userID is typing
+func (cs *CoreService) SendTyping(ctx context.Context, userID int64, toUserID int64) error {
+	err := cs.ensureMatched(ctx, userID, toUserID)
+	if err != nil {
+		return err
+	}
+
+	return cs.EventRepo.PublishEvent(ctx, toUserID, model.Event{
+		Type:       util.EventTypeTyping,
+		FromUserID: userID,
+	})
+}
+
+// publishEvent delivers event on a best effort basis, a failure must not fail the originating request
+func (cs *CoreService) publishEvent(ctx context.Context, userID int64, event model.Event) {
+	err := cs.EventRepo.PublishEvent(ctx, userID, event)
+	if err != nil {
+		log.Printf("publish %s event to %d failed: %v", event.Type, userID, err)
+	}
+}
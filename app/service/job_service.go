@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+
+	pb "github.com/atrariksa/kenalan-core/app/external/grpc_client"
+)
+
+// HandleDelayedJob runs a job taken off the delayed job queue
+func (cs *CoreService) HandleDelayedJob(ctx context.Context, job model.DelayedJob) error {
+	switch job.Type {
+	case util.JobTypeExpireSubscription:
+		return cs.refreshEntitlements(ctx, job.Email)
+	case util.JobTypeRecoverOrder:
+		return cs.recoverOrder(ctx, job)
+	}
+	return fmt.Errorf("unknown job type %s", job.Type)
+}
+
+// recoverOrder fulfills an order left processing, e.g. because core stopped while fulfilling it. The payment has
+// settled, so the order is fulfilled again rather than waiting for a gateway retry that may never come. An order
+// still within processing-timeout is looked at again once it could have timed out.
+func (cs *CoreService) recoverOrder(ctx context.Context, job model.DelayedJob) error {
+	order, err := cs.OrderRepo.GetOrder(ctx, job.OrderID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrOrderNotFound) {
+			return nil
+		}
+		return err
+	}
+	if order.Status != util.OrderStatusProcessing {
+		return nil
+	}
+
+	now := util.TimeNow()
+	timeout := cs.Cfg.PaymentConfig.ProcessingTimeout
+	order, err = cs.OrderRepo.ClaimStaleOrder(ctx, order.ID, util.OrderStatusProcessing, now.Add(-timeout), now)
+	if errors.Is(err, apperror.ErrOrderStatusConflict) {
+		if order.Status != util.OrderStatusProcessing {
+			return nil
+		}
+		return cs.scheduleOrderRecovery(ctx, job, order.UpdatedAt)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("recovering order %s left processing since %s", order.ID, order.UpdatedAt.Format(time.RFC3339))
+	err = cs.fulfillOrder(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	_, err = cs.OrderRepo.TransitionOrderStatus(ctx, order.ID, util.OrderStatusProcessing, util.OrderStatusPaid, util.TimeNow())
+	return err
+}
+
+// scheduleOrderRecovery queues job to recover its order in case it is still processing processing-timeout after
+// processingSince
+func (cs *CoreService) scheduleOrderRecovery(ctx context.Context, job model.DelayedJob, processingSince time.Time) error {
+	job.Type = util.JobTypeRecoverOrder
+	job.RunAt = processingSince.Add(cs.Cfg.PaymentConfig.ProcessingTimeout)
+	return cs.JobRepo.ScheduleJob(ctx, job)
+}
+
+// refreshEntitlements recomputes the cached entitlements of an active viewing session from user service,
+// so a renewed subscription is kept while a lapsed one is turned off
+func (cs *CoreService) refreshEntitlements(ctx context.Context, email string) error {
+	viewProfileData, err := cs.RedisRepo.GetViewProfile(ctx, fmt.Sprintf(KeyViewProfile, email))
+	if err != nil {
+		return err
+	}
+	if viewProfileData.Email == "" {
+		// no session cached, entitlements are computed on next use
+		return nil
+	}
+
+	rUser, err := HandleGetUserSubscription(ctx, cs.UserClient, email)
+	if err != nil {
+		return apperror.ErrInternal
+	}
+
+	cs.applyEntitlements(ctx, &viewProfileData, rUser.Subscriptions)
+	return cs.RedisRepo.StoreViewProfile(ctx, fmt.Sprintf(KeyViewProfile, email), viewProfileData)
+}
+
+// applyEntitlements sets the entitlements granted by active subscriptions along with the first of their expiries,
+// and schedules those expiries
+func (cs *CoreService) applyEntitlements(ctx context.Context, viewProfileData *model.ViewProfile, subscriptions []*pb.UserSubscription) {
+	now := util.TimeNow()
+	viewProfileData.IsUnlimitedSwipe = false
+	viewProfileData.CanSeeLikes = false
+	viewProfileData.EntitlementsExpiredAt = time.Time{}
+	for i := 0; i < len(subscriptions); i++ {
+		expiredAt, isActive := subscriptionExpiry(subscriptions[i], now)
+		if !isActive {
+			continue
+		}
+
+		product, err := cs.ProductRepo.GetProduct(ctx, subscriptions[i].ProductCode)
+		if err != nil {
+			continue
+		}
+
+		if grantEntitlements(viewProfileData, product, expiredAt) {
+			cs.scheduleSubscriptionExpiry(ctx, viewProfileData.Email, product.Code, expiredAt)
+		}
+	}
+}
+
+// grantEntitlements sets the session entitlements granted by product until expiredAt, it reports whether any
+// was granted
+func grantEntitlements(viewProfileData *model.ViewProfile, product model.Product, expiredAt time.Time) bool {
+	var isGranted bool
+	if product.HasEntitlement(util.EntitlementUnlimitedSwipe) {
+		viewProfileData.IsUnlimitedSwipe = true
+		isGranted = true
+	}
+	if product.HasEntitlement(util.EntitlementSeeWhoLikedYou) {
+		viewProfileData.CanSeeLikes = true
+		isGranted = true
+	}
+	if isGranted && (viewProfileData.EntitlementsExpiredAt.IsZero() || expiredAt.Before(viewProfileData.EntitlementsExpiredAt)) {
+		viewProfileData.EntitlementsExpiredAt = expiredAt
+	}
+	return isGranted
+}
+
+// hasEntitlement reports whether any active subscription grants entitlement
+func (cs *CoreService) hasEntitlement(ctx context.Context, subscriptions []*pb.UserSubscription, entitlement string) bool {
+	now := util.TimeNow()
+	for i := 0; i < len(subscriptions); i++ {
+		if _, isActive := subscriptionExpiry(subscriptions[i], now); !isActive {
+			continue
+		}
+
+		product, err := cs.ProductRepo.GetProduct(ctx, subscriptions[i].ProductCode)
+		if err != nil {
+			continue
+		}
+		if product.HasEntitlement(entitlement) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cs *CoreService) scheduleSubscriptionExpiry(ctx context.Context, email string, productCode string, expiredAt time.Time) {
+	err := cs.JobRepo.ScheduleJob(ctx, model.DelayedJob{
+		Type:        util.JobTypeExpireSubscription,
+		Email:       email,
+		ProductCode: productCode,
+		RunAt:       expiredAt,
+	})
+	if err != nil {
+		log.Printf("schedule expiry of %s for %s failed: %v", productCode, email, err)
+	}
+}
+
+// subscriptionExpiry returns when subscription expires and whether it is active at now
+func subscriptionExpiry(subscription *pb.UserSubscription, now time.Time) (time.Time, bool) {
+	if !subscription.IsActive {
+		return time.Time{}, false
+	}
+
+	expiredAt, err := util.ToDateTimeYYYYMMDDTHHmmss(subscription.ExpiredAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return expiredAt, now.Before(expiredAt)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+)
+
+// GetLikes lists users who liked the caller. Without the SeeWhoLikedYou entitlement only the total
+// and redacted entries are returned.
+func (cs *CoreService) GetLikes(ctx context.Context, lr model.LikesRequest) ([]model.Like, int64, error) {
+	principal := lr.Principal
+	viewProfileData, err := cs.getViewProfileData(ctx, principal.Email)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	likes, total, err := cs.RedisRepo.GetLikedBy(ctx, principal.UserID, (lr.Page-1)*lr.PageSize, lr.PageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := 0; i < len(likes); i++ {
+		if !viewProfileData.CanSeeLikes {
+			likes[i].Profile = nil
+			likes[i].IsRedacted = true
+			continue
+		}
+
+		profile, err := cs.RedisRepo.GetProfile(ctx, likes[i].Profile.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		likes[i].Profile = &profile
+	}
+
+	return likes, total, nil
+}
+
+// LikeBack likes a user straight from the inbox, which always results in a match
+func (cs *CoreService) LikeBack(ctx context.Context, lbr model.LikeBackRequest) (*model.Match, error) {
+	principal := lbr.Principal
+	viewProfileData, err := cs.getViewProfileData(ctx, principal.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if !viewProfileData.CanSeeLikes {
+		return nil, apperror.ErrSubscriptionRequired
+	}
+
+	isLiked, err := cs.RedisRepo.IsLiked(ctx, lbr.LikerUserID, principal.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLiked {
+		return nil, apperror.ErrLikeNotFound
+	}
+
+	// a like left by an unmatched user is not listed by GetLikes either
+	isUnmatched, err := cs.RedisRepo.IsUnmatched(ctx, principal.UserID, lbr.LikerUserID)
+	if err != nil {
+		return nil, err
+	}
+	if isUnmatched {
+		return nil, apperror.ErrLikeNotFound
+	}
+
+	return cs.like(ctx, principal.UserID, lbr.LikerUserID)
+}
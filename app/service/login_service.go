@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+)
+
+// loginSubjects returns the failed login counters a login attempt is checked against
+func loginSubjects(loginRequest model.LoginRequest) []string {
+	subjects := []string{"email:" + strings.ToLower(strings.TrimSpace(loginRequest.Email))}
+	if loginRequest.IP != "" {
+		subjects = append(subjects, "ip:"+loginRequest.IP)
+	}
+	return subjects
+}
+
+// checkLoginLockout rejects the attempt while its email or IP is locked out
+func (cs *CoreService) checkLoginLockout(ctx context.Context, loginRequest model.LoginRequest) error {
+	lockout, err := cs.LoginAttemptRepo.GetLoginLockout(ctx, loginSubjects(loginRequest)...)
+	if err != nil {
+		return err
+	}
+	if lockout > 0 {
+		return apperror.ErrTooManyLoginAttempts.WithRetryAfter(lockout)
+	}
+	return nil
+}
+
+// recordLoginFailure counts the failed attempt against its email and IP. Once a subject reaches its threshold
+// within the window it is locked out, doubling the lockout with every further failure up to lockout-max.
+func (cs *CoreService) recordLoginFailure(ctx context.Context, loginRequest model.LoginRequest) {
+	loginConfig := cs.Cfg.LoginConfig
+	for _, subject := range loginSubjects(loginRequest) {
+		failures, err := cs.LoginAttemptRepo.RecordLoginFailure(ctx, subject, loginConfig.Window)
+		if err != nil {
+			log.Printf("record login failure of %s failed: %v", subject, err)
+			continue
+		}
+
+		maxFailures := loginConfig.MaxFailuresPerEmail
+		if strings.HasPrefix(subject, "ip:") {
+			maxFailures = loginConfig.MaxFailuresPerIP
+		}
+		if failures < maxFailures {
+			continue
+		}
+
+		err = cs.LoginAttemptRepo.LockLogin(ctx, subject, loginLockout(loginConfig.LockoutBase, loginConfig.LockoutMax, failures-maxFailures))
+		if err != nil {
+			log.Printf("lock login of %s failed: %v", subject, err)
+		}
+	}
+}
+
+// resetLoginFailures clears the email counter after a successful login, the IP counter is left to expire
+// so one valid account can't be used to keep guessing others
+func (cs *CoreService) resetLoginFailures(ctx context.Context, loginRequest model.LoginRequest) {
+	subject := loginSubjects(loginRequest)[0]
+	err := cs.LoginAttemptRepo.ResetLoginFailures(ctx, subject)
+	if err != nil {
+		log.Printf("reset login failures of %s failed: %v", subject, err)
+	}
+}
+
+func loginLockout(base time.Duration, max time.Duration, exponent int64) time.Duration {
+	lockout := float64(base) * math.Pow(2, float64(exponent))
+	if lockout > float64(max) {
+		return max
+	}
+	return time.Duration(lockout)
+}
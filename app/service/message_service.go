@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+)
+
+func (cs *CoreService) SendMessage(ctx context.Context, smr model.SendMessageRequest) (model.Message, error) {
+	var message model.Message
+	principal := smr.Principal
+	err := cs.ensureMatched(ctx, principal.UserID, smr.MatchedUserID)
+	if err != nil {
+		return message, err
+	}
+
+	message, err = cs.MessageRepo.StoreMessage(ctx, model.Message{
+		SenderID:    principal.UserID,
+		RecipientID: smr.MatchedUserID,
+		Text:        smr.Text,
+		SentAt:      util.TimeNow(),
+	})
+	if err != nil {
+		return message, err
+	}
+
+	cs.publishEvent(ctx, smr.MatchedUserID, model.Event{
+		Type:       util.EventTypeMessage,
+		FromUserID: principal.UserID,
+		Message:    &message,
+	})
+
+	return message, nil
+}
+
+// GetMessages returns a page of the conversation, newest first, and the cursor for the next page (0 when there is none)
+func (cs *CoreService) GetMessages(ctx context.Context, mr model.MessagesRequest) ([]model.Message, int64, error) {
+	principal := mr.Principal
+	err := cs.ensureMatched(ctx, principal.UserID, mr.MatchedUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	messages, err := cs.MessageRepo.GetMessages(ctx, principal.UserID, mr.MatchedUserID, mr.Cursor, mr.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	viewerLastReadID, err := cs.MessageRepo.GetLastReadID(ctx, principal.UserID, mr.MatchedUserID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	otherLastReadID, err := cs.MessageRepo.GetLastReadID(ctx, mr.MatchedUserID, principal.UserID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := 0; i < len(messages); i++ {
+		if messages[i].SenderID == principal.UserID {
+			messages[i].IsRead = messages[i].ID <= otherLastReadID
+		} else {
+			messages[i].IsRead = messages[i].ID <= viewerLastReadID
+		}
+	}
+
+	var nextCursor int64
+	if int64(len(messages)) == mr.Limit {
+		nextCursor = messages[len(messages)-1].ID
+	}
+
+	return messages, nextCursor, nil
+}
+
+// MarkMessagesRead marks messages up to mmr.MessageID as read, or the whole conversation when MessageID is 0
+func (cs *CoreService) MarkMessagesRead(ctx context.Context, mmr model.MarkMessagesReadRequest) error {
+	principal := mmr.Principal
+	err := cs.ensureMatched(ctx, principal.UserID, mmr.MatchedUserID)
+	if err != nil {
+		return err
+	}
+
+	upToID := mmr.MessageID
+	if upToID == 0 {
+		latest, err := cs.MessageRepo.GetMessages(ctx, principal.UserID, mmr.MatchedUserID, 0, 1)
+		if err != nil {
+			return err
+		}
+		if len(latest) == 0 {
+			return nil
+		}
+		upToID = latest[0].ID
+	}
+
+	return cs.MessageRepo.MarkRead(ctx, principal.UserID, mmr.MatchedUserID, upToID)
+}
+
+func (cs *CoreService) ensureMatched(ctx context.Context, userID int64, otherUserID int64) error {
+	isMatched, err := cs.RedisRepo.IsMatched(ctx, userID, otherUserID)
+	if err != nil {
+		return err
+	}
+	if !isMatched {
+		return apperror.ErrNotMatched
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+)
+
+// ForgotPassword mails a single use reset token to the account of email. It behaves the same whether the account
+// exists or not, the mail is sent in the background so the response time doesn't tell either.
+func (cs *CoreService) ForgotPassword(ctx context.Context, fpr model.ForgotPasswordRequest) error {
+	email := strings.TrimSpace(fpr.Email)
+	isAcquired, err := cs.PasswordResetRepo.AcquirePasswordResetCooldown(ctx, email, cs.Cfg.PasswordResetConfig.Cooldown)
+	if err != nil {
+		return err
+	}
+	if !isAcquired {
+		return nil
+	}
+
+	_, err = HandleGetUserByEmail(ctx, cs.UserClient, model.LoginRequest{Email: email})
+	if err != nil {
+		if !errors.Is(err, apperror.ErrUserNotFound) {
+			return err
+		}
+		return nil
+	}
+
+	token := util.RandomID(32)
+	err = cs.PasswordResetRepo.StorePasswordResetToken(ctx, email, util.HashToken(token), cs.Cfg.PasswordResetConfig.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	cs.sendMail(model.Mail{
+		To:      email,
+		Subject: "Reset your Kenalan password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Kenalan account.\n\n"+
+			"Open the link below within %d minutes to choose a new password:\n%s\n\n"+
+			"If it wasn't you, ignore this mail, your password stays the same.",
+			int(cs.Cfg.PasswordResetConfig.TokenTTL.Minutes()), fmt.Sprintf(cs.Cfg.PasswordResetConfig.URL, token)),
+	})
+
+	return nil
+}
+
+// ResetPassword sets a new password with a token from ForgotPassword. The token can be used once, afterwards
+// every session of the account is revoked.
+func (cs *CoreService) ResetPassword(ctx context.Context, rpr model.ResetPasswordRequest) error {
+	email, err := cs.PasswordResetRepo.ConsumePasswordResetToken(ctx, util.HashToken(rpr.Token))
+	if err != nil {
+		return err
+	}
+	if email == "" {
+		return apperror.ErrInvalidResetToken
+	}
+
+	_, err = HandleUpdatePassword(ctx, cs.UserClient, email, rpr.Password)
+	if err != nil {
+		return err
+	}
+
+	err = cs.TokenRepo.RevokeTokensBefore(ctx, email, util.TimeNow(), cs.Cfg.AuthConfig.MaxTokenLifetime)
+	if err != nil {
+		return err
+	}
+	cs.resetLoginFailures(ctx, model.LoginRequest{Email: email})
+
+	return nil
+}
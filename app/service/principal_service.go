@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/external/jwt_verifier"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+)
+
+// Authenticate resolves the caller behind token. Everything acting on behalf of the caller must use
+// the returned principal rather than identifiers sent in the request.
+func (cs *CoreService) Authenticate(ctx context.Context, token string) (model.Principal, error) {
+	var principal model.Principal
+	if token == "" {
+		return principal, apperror.ErrUnauthorized
+	}
+
+	tokenHash := util.HashToken(token)
+	tokenInfo, err := cs.validateToken(ctx, tokenHash, token)
+	if err != nil {
+		return principal, err
+	}
+
+	viewProfileData, err := cs.getViewProfileData(ctx, tokenInfo.Email)
+	if err != nil {
+		// the account behind a valid token may have been deleted since
+		if errors.Is(err, apperror.ErrUserNotFound) {
+			return principal, apperror.ErrUnauthorized
+		}
+		return principal, err
+	}
+
+	return model.Principal{
+		UserID: viewProfileData.ViewerID,
+		Email:  viewProfileData.Email,
+		Gender: viewProfileData.ViewerGender,
+
+		TokenHash: tokenHash,
+		Token:     tokenInfo,
+	}, nil
+}
+
+// validateToken resolves token and rejects it when it has been revoked. Revocation is checked on every call,
+// including cached tokens, so logging out takes effect immediately.
+func (cs *CoreService) validateToken(ctx context.Context, tokenHash string, token string) (model.TokenInfo, error) {
+	tokenInfo, err := cs.resolveToken(ctx, tokenHash, token)
+	if err != nil {
+		return tokenInfo, err
+	}
+
+	err = cs.checkRevocation(ctx, tokenHash, tokenInfo)
+	if err != nil {
+		return tokenInfo, err
+	}
+	return tokenInfo, nil
+}
+
+func (cs *CoreService) checkRevocation(ctx context.Context, tokenHash string, tokenInfo model.TokenInfo) error {
+	revocation, err := cs.TokenRepo.GetTokenRevocation(ctx, tokenHash, tokenInfo.Email)
+	if err != nil {
+		return err
+	}
+	if revocation.Revokes(tokenInfo.IssuedAt) {
+		return apperror.ErrUnauthorized
+	}
+	return nil
+}
+
+// IssueWSTicket returns a single-use ticket opening a websocket as principal for clients that can't send the
+// access token in a header, it is only valid for ticket-ttl so it is harmless once it shows up in logs
+func (cs *CoreService) IssueWSTicket(ctx context.Context, principal model.Principal) (string, error) {
+	ticket := util.RandomID(32)
+	err := cs.TokenRepo.StoreWSTicket(ctx, util.HashToken(ticket), principal, cs.Cfg.WebsocketConfig.TicketTTL)
+	if err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// AuthenticateWSTicket resolves the caller a websocket ticket was issued to, the ticket can be used once and
+// is rejected when the access token it was issued with has been revoked since
+func (cs *CoreService) AuthenticateWSTicket(ctx context.Context, ticket string) (model.Principal, error) {
+	principal, err := cs.TokenRepo.ConsumeWSTicket(ctx, util.HashToken(ticket))
+	if err != nil {
+		return principal, err
+	}
+	if principal.Email == "" {
+		return principal, apperror.ErrUnauthorized
+	}
+
+	err = cs.checkRevocation(ctx, principal.TokenHash, principal.Token)
+	if err != nil {
+		return model.Principal{}, err
+	}
+	return principal, nil
+}
+
+// resolveToken checks token against the cache first, then verifies it locally when it is a JWT signed by a
+// known key. Opaque tokens, JWTs signed by an unknown key and, with check-revocation, every token are left
+// to auth service. Positive results are cached for token-cache-ttl, never beyond the token's expiry.
+func (cs *CoreService) resolveToken(ctx context.Context, tokenHash string, token string) (model.TokenInfo, error) {
+	tokenInfo, err := cs.TokenRepo.GetValidToken(ctx, tokenHash)
+	if err != nil {
+		log.Printf("get cached token failed: %v", err)
+	}
+	if tokenInfo.Email != "" {
+		return tokenInfo, nil
+	}
+
+	tokenInfo = model.TokenInfo{}
+	isVerified := false
+	if cs.JWTVerifier != nil {
+		claims, err := cs.JWTVerifier.Verify(token)
+		switch err {
+		case nil:
+			tokenInfo = model.TokenInfo{
+				Email:     claims.Email,
+				TokenID:   claims.ID,
+				IssuedAt:  claims.IssuedAt,
+				ExpiresAt: claims.ExpiresAt,
+			}
+			isVerified = !cs.Cfg.AuthConfig.JWT.CheckRevocation
+		case jwt_verifier.ErrNotJWT, jwt_verifier.ErrUnknownKey:
+		default:
+			return tokenInfo, apperror.ErrUnauthorized
+		}
+	}
+
+	if !isVerified {
+		rToken, err := HandleIsTokenValid(ctx, cs.AuthClient, token)
+		if err != nil {
+			return tokenInfo, err
+		}
+		if rToken.Email == "" {
+			return tokenInfo, apperror.ErrInvalidToken
+		}
+		tokenInfo.Email = rToken.Email
+	}
+
+	// opaque tokens don't tell when they were issued, they are marked when core hands them out and the first
+	// time core sees one stands in for tokens issued elsewhere
+	if tokenInfo.IssuedAt.IsZero() {
+		tokenInfo.IssuedAt, err = cs.TokenRepo.MarkTokenSeen(ctx, tokenHash, util.TimeNow(), cs.tokenLifetime(tokenInfo))
+		if err != nil {
+			return tokenInfo, err
+		}
+	}
+
+	ttl := cs.Cfg.AuthConfig.TokenCacheTTL
+	if !tokenInfo.ExpiresAt.IsZero() {
+		if untilExpiry := tokenInfo.ExpiresAt.Sub(util.TimeNow()); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+	}
+	if ttl > 0 {
+		err = cs.TokenRepo.StoreValidToken(ctx, tokenHash, tokenInfo, ttl)
+		if err != nil {
+			log.Printf("cache token failed: %v", err)
+		}
+	}
+
+	return tokenInfo, nil
+}
+
+// tokenLifetime is how long tokenInfo may still be used, max-token-lifetime when its expiry is unknown
+func (cs *CoreService) tokenLifetime(tokenInfo model.TokenInfo) time.Duration {
+	if tokenInfo.ExpiresAt.IsZero() {
+		return cs.Cfg.AuthConfig.MaxTokenLifetime
+	}
+	return tokenInfo.ExpiresAt.Sub(util.TimeNow())
+}
+
+// Logout revokes the token of principal and the refresh tokens issued with it, or with allDevices every token
+// of principal issued so far
+func (cs *CoreService) Logout(ctx context.Context, principal model.Principal, allDevices bool) error {
+	if allDevices {
+		return cs.TokenRepo.RevokeTokensBefore(ctx, principal.Email, util.TimeNow(), cs.Cfg.AuthConfig.MaxTokenLifetime)
+	}
+
+	familyID, err := cs.TokenRepo.GetAccessTokenFamilyID(ctx, principal.TokenHash)
+	if err != nil {
+		return err
+	}
+	if familyID != "" {
+		err = cs.TokenRepo.RevokeRefreshTokenFamily(ctx, familyID)
+		if err != nil {
+			return err
+		}
+	}
+
+	ttl := cs.tokenLifetime(principal.Token)
+	if ttl <= 0 {
+		return nil
+	}
+	return cs.TokenRepo.RevokeToken(ctx, principal.TokenHash, ttl)
+}
+
+func (cs *CoreService) audit(ctx context.Context, entry model.AuditEntry) {
+	entry.At = util.TimeNow()
+	err := cs.AuditRepo.StoreAuditEntry(ctx, entry)
+	if err != nil {
+		log.Printf("store audit entry %s of order %s failed: %v", entry.Action, entry.OrderID, err)
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/atrariksa/kenalan-core/app/apperror"
+	"github.com/atrariksa/kenalan-core/app/model"
+	"github.com/atrariksa/kenalan-core/app/util"
+)
+
+// RefreshToken exchanges the latest refresh token of a family for a new token pair. Refresh tokens are single
+// use, presenting one that was already rotated means it leaked, so the whole family and its access token are revoked.
+func (cs *CoreService) RefreshToken(ctx context.Context, rtr model.RefreshTokenRequest) (model.TokenPair, error) {
+	var tokenPair model.TokenPair
+	familyID, _, ok := strings.Cut(rtr.RefreshToken, ".")
+	if !ok {
+		return tokenPair, apperror.ErrInvalidRefreshToken
+	}
+
+	refreshTokenHash := util.HashToken(rtr.RefreshToken)
+	family, err := cs.TokenRepo.GetRefreshTokenFamily(ctx, familyID)
+	if err != nil {
+		return tokenPair, err
+	}
+	if family.Email == "" || family.IsRevoked {
+		return tokenPair, apperror.ErrInvalidRefreshToken
+	}
+	if family.RefreshTokenHash != refreshTokenHash {
+		log.Printf("refresh token reused in family %s, revoking it", family.ID)
+		cs.revokeRefreshTokenFamily(ctx, family.ID)
+		return tokenPair, apperror.ErrInvalidRefreshToken
+	}
+
+	// families started before logging out of all devices are revoked with their tokens
+	revocation, err := cs.TokenRepo.GetTokenRevocation(ctx, refreshTokenHash, family.Email)
+	if err != nil {
+		return tokenPair, err
+	}
+	if revocation.Revokes(family.CreatedAt) {
+		return tokenPair, apperror.ErrInvalidRefreshToken
+	}
+
+	rToken, err := HandleGetToken(ctx, cs.AuthClient, model.LoginRequest{Email: family.Email})
+	if err != nil {
+		return tokenPair, err
+	}
+	err = cs.markTokenIssued(ctx, rToken.Token)
+	if err != nil {
+		return tokenPair, err
+	}
+
+	tokenPair = model.TokenPair{
+		AccessToken:  rToken.Token,
+		RefreshToken: family.ID + "." + util.RandomID(32),
+	}
+	isRotated, err := cs.TokenRepo.RotateRefreshToken(ctx, family.ID, refreshTokenHash, util.HashToken(tokenPair.RefreshToken),
+		util.HashToken(tokenPair.AccessToken), cs.Cfg.AuthConfig.RefreshTokenTTL, cs.Cfg.AuthConfig.MaxTokenLifetime)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	if !isRotated {
+		// another request rotated the same refresh token first
+		log.Printf("refresh token reused concurrently in family %s, revoking it", family.ID)
+		cs.revokeRefreshTokenFamily(ctx, family.ID)
+		cs.revokeToken(ctx, util.HashToken(tokenPair.AccessToken))
+		return model.TokenPair{}, apperror.ErrInvalidRefreshToken
+	}
+
+	return tokenPair, nil
+}
+
+// issueTokenPair starts a new refresh token family for a fresh login
+func (cs *CoreService) issueTokenPair(ctx context.Context, email string, accessToken string) (model.TokenPair, error) {
+	family := model.RefreshTokenFamily{
+		ID:              util.RandomID(16),
+		Email:           email,
+		AccessTokenHash: util.HashToken(accessToken),
+		CreatedAt:       util.TimeNow(),
+	}
+	tokenPair := model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: family.ID + "." + util.RandomID(32),
+	}
+	family.RefreshTokenHash = util.HashToken(tokenPair.RefreshToken)
+
+	err := cs.markTokenIssued(ctx, accessToken)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	err = cs.TokenRepo.StoreRefreshTokenFamily(ctx, family, cs.Cfg.AuthConfig.RefreshTokenTTL, cs.Cfg.AuthConfig.MaxTokenLifetime)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	return tokenPair, nil
+}
+
+// markTokenIssued records now as the issue time of accessToken, opaque tokens don't carry it and one used for
+// the first time after logging out of all devices must still be revoked
+func (cs *CoreService) markTokenIssued(ctx context.Context, accessToken string) error {
+	_, err := cs.TokenRepo.MarkTokenSeen(ctx, util.HashToken(accessToken), util.TimeNow(), cs.Cfg.AuthConfig.MaxTokenLifetime)
+	return err
+}
+
+// revokeRefreshTokenFamily revokes the family and the access token it issued last
+func (cs *CoreService) revokeRefreshTokenFamily(ctx context.Context, familyID string) {
+	err := cs.TokenRepo.RevokeRefreshTokenFamily(ctx, familyID)
+	if err != nil {
+		log.Printf("revoke refresh token family %s failed: %v", familyID, err)
+	}
+
+	family, err := cs.TokenRepo.GetRefreshTokenFamily(ctx, familyID)
+	if err != nil {
+		log.Printf("get refresh token family %s failed: %v", familyID, err)
+		return
+	}
+	if family.AccessTokenHash != "" {
+		cs.revokeToken(ctx, family.AccessTokenHash)
+	}
+}
+
+func (cs *CoreService) revokeToken(ctx context.Context, tokenHash string) {
+	err := cs.TokenRepo.RevokeToken(ctx, tokenHash, cs.Cfg.AuthConfig.MaxTokenLifetime)
+	if err != nil {
+		log.Printf("revoke token failed: %v", err)
+	}
+}
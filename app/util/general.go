@@ -1,20 +1,46 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"time"
+	"unicode"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-const ErrUnauthorized = "unauthorized"
-const ErrInternalError = "internal error"
-const ErrInvalidToken = "invalid token"
-const ErrProductNotFound = "product not found"
+const EntitlementUnlimitedSwipe = "unlimited_swipe"
+const EntitlementVerified = "verified"
+const EntitlementSeeWhoLikedYou = "see_who_liked_you"
 
-const CodeInvalidToken = 40
+const DefaultPageSize = 20
+const MaxPageSize = 100
 
-const UnlimitedSwipeProductCode = "SKU001"
-const AccountVerifiedProductCode = "SKU002"
+const MaxMessageLength = 1000
+
+const MaxNextProfileAttempts = 3
+
+const EventTypeMatch = "match"
+const EventTypeMessage = "message"
+const EventTypeTyping = "typing"
+const EventTypePing = "ping"
+const EventTypePong = "pong"
+
+const OrderStatusPending = "pending"
+const OrderStatusProcessing = "processing"
+const OrderStatusPaid = "paid"
+const OrderStatusFailed = "failed"
+
+const IdempotencyStatusProcessing = "processing"
+const IdempotencyStatusCompleted = "completed"
+
+const AuditActionGiftOrderCreated = "gift_order_created"
+const AuditActionGiftFulfilled = "gift_fulfilled"
+
+const JobTypeExpireSubscription = "expire_subscription"
+const JobTypeRecoverOrder = "recover_order"
 
 const GenderMale = "M"
 const GenderFemale = "F"
@@ -34,9 +60,43 @@ func ToDateTimeYYYYMMDDTHHmmss(dateString string) (dt time.Time, err error) {
 	return time.Parse(DateFormatYYYYMMDDTHHmmss, dateString)
 }
 
+// NextMidnight returns the start of the day following t in loc
+func NextMidnight(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+}
+
+// DummyPasswordHash is validated against when the user doesn't exist so unknown emails take as long as wrong passwords
+const DummyPasswordHash = "$2a$10$Afj5U7ZNcKOh.Rm6cF/18eugrea3OFc/VpmHvGV9t2HrkmcLBhDBm"
+
 func ValidatePassword(givenPlainTextPassword string, storedHashedPassword string) error {
 	password := []byte(givenPlainTextPassword)
 	hashedPassword := []byte(storedHashedPassword)
 	// Comparing the password with the hash
 	return bcrypt.CompareHashAndPassword(hashedPassword, password)
 }
+
+// SanitizeText trims the text and drops control characters other than new lines. The text is stored as sent,
+// it is escaped when rendered: the JSON encoder escapes <, > and & and HTML clients must escape it themselves.
+func SanitizeText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if r != '\n' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.TrimSpace(text)
+}
+
+// RandomID returns a random hex string of n bytes
+func RandomID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// HashToken returns the hex SHA-256 of token, tokens are stored by hash so a dump of redis can't be replayed
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
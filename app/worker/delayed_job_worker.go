@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/atrariksa/kenalan-core/app/repository"
+	"github.com/atrariksa/kenalan-core/app/service"
+	"github.com/atrariksa/kenalan-core/app/util"
+	"github.com/atrariksa/kenalan-core/config"
+)
+
+// DelayedJobWorker polls the delayed job queue and hands due jobs to the core service
+type DelayedJobWorker struct {
+	JobRepo     repository.IJobRepository
+	CoreService service.ICoreService
+	Cfg         *config.Config
+}
+
+func NewDelayedJobWorker(
+	jobRepo repository.IJobRepository,
+	coreService service.ICoreService,
+	cfg *config.Config) *DelayedJobWorker {
+
+	return &DelayedJobWorker{
+		JobRepo:     jobRepo,
+		CoreService: coreService,
+		Cfg:         cfg,
+	}
+}
+
+// Run polls until ctx is done
+func (w *DelayedJobWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Cfg.WorkerConfig.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx)
+		}
+	}
+}
+
+// poll leases due jobs and runs them. A job is only taken off the queue once it ran or was rescheduled, one
+// failing max-attempts times is buried in the dead job list rather than dropped.
+func (w *DelayedJobWorker) poll(ctx context.Context) {
+	jobs, err := w.JobRepo.LeaseDueJobs(ctx, util.TimeNow(), w.Cfg.WorkerConfig.BatchSize, w.Cfg.WorkerConfig.Lease)
+	if err != nil {
+		log.Printf("lease due jobs failed: %v", err)
+		return
+	}
+
+	for _, job := range jobs {
+		err = w.CoreService.HandleDelayedJob(ctx, job)
+		if err == nil {
+			err = w.JobRepo.CompleteJob(ctx, job)
+			if err != nil {
+				log.Printf("complete job %s for %s failed: %v", job.Type, job.Email, err)
+			}
+			continue
+		}
+
+		next := job
+		next.Attempt++
+		if next.Attempt >= w.Cfg.WorkerConfig.MaxAttempts {
+			log.Printf("job %s for %s buried after %d attempts: %v", job.Type, job.Email, next.Attempt, err)
+			err = w.JobRepo.BuryJob(ctx, job, util.TimeNow())
+			if err != nil {
+				log.Printf("bury job %s for %s failed: %v", job.Type, job.Email, err)
+			}
+			continue
+		}
+
+		log.Printf("job %s for %s failed, retrying: %v", job.Type, job.Email, err)
+		next.RunAt = util.TimeNow().Add(w.Cfg.WorkerConfig.RetryDelay)
+		err = w.JobRepo.RescheduleJob(ctx, job, next)
+		if err != nil {
+			log.Printf("reschedule job %s for %s failed: %v", job.Type, job.Email, err)
+		}
+	}
+}
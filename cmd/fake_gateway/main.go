@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/atrariksa/kenalan-core/app/external/payment_gateway"
+	"github.com/atrariksa/kenalan-core/config"
+)
+
+// fake_gateway runs the fake payment gateway on payment.gateway-url, sending callbacks to the local core server
+func main() {
+	cfg := config.GetConfig()
+	gatewayURL, err := url.Parse(cfg.PaymentConfig.GatewayURL)
+	if err != nil {
+		log.Fatalf("invalid payment.gateway-url: %v", err)
+	}
+
+	webhookURL := fmt.Sprintf("http://localhost:%v/v1/kenalan/payments/webhook", cfg.ServerConfig.Port)
+	fake := payment_gateway.NewFakePaymentGateway(cfg.PaymentConfig.GatewayURL, webhookURL, cfg.PaymentConfig.WebhookSecret)
+
+	log.Printf("fake payment gateway listening on %s", gatewayURL.Host)
+	log.Fatal(http.ListenAndServe(gatewayURL.Host, fake))
+}
@@ -3,25 +3,90 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	ServerConfig     ServerConfig     `mapstructure:"server"`
-	UserServerConfig UserServerConfig `mapstructure:"user-server"`
-	AuthServerConfig UserServerConfig `mapstructure:"auth-server"`
-	RedisConfig      RedisConfig      `mapstructure:"redis"`
+	ServerConfig            ServerConfig            `mapstructure:"server"`
+	UserServerConfig        UserServerConfig        `mapstructure:"user-server"`
+	AuthServerConfig        UserServerConfig        `mapstructure:"auth-server"`
+	RedisConfig             RedisConfig             `mapstructure:"redis"`
+	QuotaConfig             QuotaConfig             `mapstructure:"quota"`
+	HistoryConfig           HistoryConfig           `mapstructure:"history"`
+	WorkerConfig            WorkerConfig            `mapstructure:"worker"`
+	Products                []ProductConfig         `mapstructure:"products"`
+	PaymentConfig           PaymentConfig           `mapstructure:"payment"`
+	IdempotencyConfig       IdempotencyConfig       `mapstructure:"idempotency"`
+	AuthConfig              AuthConfig              `mapstructure:"auth"`
+	LoginConfig             LoginConfig             `mapstructure:"login"`
+	PasswordResetConfig     PasswordResetConfig     `mapstructure:"password-reset"`
+	MailConfig              MailConfig              `mapstructure:"mail"`
+	EmailVerificationConfig EmailVerificationConfig `mapstructure:"email-verification"`
+	WebsocketConfig         WebsocketConfig         `mapstructure:"websocket"`
 }
 
+// ServerConfig BehindProxy makes the client IP come from X-Forwarded-For set by a proxy on a private network,
+// otherwise the header is ignored so clients can't spoof their IP. RequestTimeout bounds everything a request does,
+// including every downstream call and its retries, websockets are not bound by it. ShutdownTimeout is how long
+// in-flight requests get to finish on shutdown.
 type ServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port int    `mapstructure:"port"`
+	Host            string        `mapstructure:"host"`
+	Port            int           `mapstructure:"port"`
+	BehindProxy     bool          `mapstructure:"behind-proxy"`
+	RequestTimeout  time.Duration `mapstructure:"request-timeout"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdown-timeout"`
 }
 
+// UserServerConfig is the address of a downstream gRPC service, Endpoints lists host:port of every instance,
+// otherwise every address Host resolves to is used. LoadBalancing is pick_first, round_robin or least_request,
+// HealthCheck skips instances reporting HealthCheckService as not serving. While calls are in flight the
+// connection is pinged every KeepaliveTime and dropped when a ping isn't answered within KeepaliveTimeout.
+// KeepaliveTime must not be below MinTime of the service's keepalive EnforcementPolicy, 5m by default, or the
+// service closes the connection for pinging too often. Each call must finish within Deadline, or its entry in
+// Deadlines keyed by the lower case method name.
 type UserServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port int    `mapstructure:"port"`
+	Host               string                   `mapstructure:"host"`
+	Port               int                      `mapstructure:"port"`
+	Endpoints          []string                 `mapstructure:"endpoints"`
+	LoadBalancing      string                   `mapstructure:"load-balancing"`
+	HealthCheck        bool                     `mapstructure:"health-check"`
+	HealthCheckService string                   `mapstructure:"health-check-service"`
+	KeepaliveTime      time.Duration            `mapstructure:"keepalive-time"`
+	KeepaliveTimeout   time.Duration            `mapstructure:"keepalive-timeout"`
+	Deadline           time.Duration            `mapstructure:"deadline"`
+	Deadlines          map[string]time.Duration `mapstructure:"deadlines"`
+	Retry              RetryConfig              `mapstructure:"retry"`
+	CircuitBreaker     CircuitBreakerConfig     `mapstructure:"circuit-breaker"`
+	TLS                TLSConfig                `mapstructure:"tls"`
+}
+
+// TLSConfig the server certificate is verified against CAFile, or the system CAs when it is empty, for
+// ServerName, or the host of the endpoint connected to when it is empty. CertFile and KeyFile are the client certificate sent
+// for mutual TLS. Changed files are picked up within ReloadInterval.
+type TLSConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	CAFile         string        `mapstructure:"ca-file"`
+	CertFile       string        `mapstructure:"cert-file"`
+	KeyFile        string        `mapstructure:"key-file"`
+	ServerName     string        `mapstructure:"server-name"`
+	ReloadInterval time.Duration `mapstructure:"reload-interval"`
+}
+
+// RetryConfig idempotent calls are tried up to MaxAttempts times, waiting a random delay up to
+// BaseDelay*2^(retry-1) capped at MaxDelay before each retry
+type RetryConfig struct {
+	MaxAttempts int           `mapstructure:"max-attempts"`
+	BaseDelay   time.Duration `mapstructure:"base-delay"`
+	MaxDelay    time.Duration `mapstructure:"max-delay"`
+}
+
+// CircuitBreakerConfig calls fail fast for OpenTimeout after FailureThreshold consecutive failures,
+// a FailureThreshold of 0 disables the breaker
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `mapstructure:"failure-threshold"`
+	OpenTimeout      time.Duration `mapstructure:"open-timeout"`
 }
 
 type AuthServerConfig struct {
@@ -35,6 +100,128 @@ type RedisConfig struct {
 	DB       int    `mapstructure:"db"`
 }
 
+type QuotaConfig struct {
+	DailySwipeLimit int64  `mapstructure:"daily-swipe-limit"`
+	DefaultTimezone string `mapstructure:"default-timezone"`
+}
+
+// HistoryConfig bounds the viewed profile history, entries older than Retention are dropped and
+// only the latest MaxExcludeIDs are sent as exclusion to user service
+type HistoryConfig struct {
+	Retention     time.Duration `mapstructure:"retention"`
+	MaxExcludeIDs int64         `mapstructure:"max-exclude-ids"`
+}
+
+// WorkerConfig a due job is leased to one worker for Lease, it runs again when its worker stops before finishing
+// it, so Lease must exceed the time a job may take
+type WorkerConfig struct {
+	PollInterval time.Duration `mapstructure:"poll-interval"`
+	BatchSize    int64         `mapstructure:"batch-size"`
+	MaxAttempts  int           `mapstructure:"max-attempts"`
+	RetryDelay   time.Duration `mapstructure:"retry-delay"`
+	Lease        time.Duration `mapstructure:"lease"`
+}
+
+// ProductConfig is an entry of the product catalog, Price is in the smallest unit of Currency
+type ProductConfig struct {
+	Code         string        `mapstructure:"code"`
+	Name         string        `mapstructure:"name"`
+	Price        int64         `mapstructure:"price"`
+	Currency     string        `mapstructure:"currency"`
+	Duration     time.Duration `mapstructure:"duration"`
+	Entitlements []string      `mapstructure:"entitlements"`
+}
+
+// PaymentConfig an order still processing ProcessingTimeout after it was paid is taken to be left behind by a
+// crash and fulfilled again, it must be well above the request timeout
+type PaymentConfig struct {
+	GatewayURL        string        `mapstructure:"gateway-url"`
+	APIKey            string        `mapstructure:"api-key"`
+	WebhookSecret     string        `mapstructure:"webhook-secret"`
+	ProcessingTimeout time.Duration `mapstructure:"processing-timeout"`
+}
+
+// IdempotencyConfig a request in progress holds its key for LockTTL, so a crash mid request doesn't block the key
+// for long, it must exceed server.request-timeout. The response of a completed request is kept for TTL.
+type IdempotencyConfig struct {
+	LockTTL time.Duration `mapstructure:"lock-ttl"`
+	TTL     time.Duration `mapstructure:"ttl"`
+}
+
+// AuthConfig TokenCacheTTL is how long a validated token is trusted without checking it again.
+// MaxTokenLifetime must be at least the lifetime of tokens issued by auth service, revocations are kept that long
+// when the token's own expiry is unknown. A refresh token family expires after RefreshTokenTTL without being used.
+type AuthConfig struct {
+	TokenCacheTTL    time.Duration `mapstructure:"token-cache-ttl"`
+	MaxTokenLifetime time.Duration `mapstructure:"max-token-lifetime"`
+	RefreshTokenTTL  time.Duration `mapstructure:"refresh-token-ttl"`
+	JWT              JWTConfig     `mapstructure:"jwt"`
+}
+
+// JWTConfig configures local verification of JWTs issued by auth service. Only one key source is used,
+// PublicKeyFile is preferred over JWKSFile which is preferred over JWKSURL. With no source configured every
+// token is checked by auth service. CheckRevocation still asks auth service about locally valid tokens.
+type JWTConfig struct {
+	PublicKeyFile   string `mapstructure:"public-key-file"`
+	JWKSFile        string `mapstructure:"jwks-file"`
+	JWKSURL         string `mapstructure:"jwks-url"`
+	Issuer          string `mapstructure:"issuer"`
+	Audience        string `mapstructure:"audience"`
+	EmailClaim      string `mapstructure:"email-claim"`
+	CheckRevocation bool   `mapstructure:"check-revocation"`
+}
+
+// LoginConfig locks out an email or IP once it has failed MaxFailuresPer* logins within Window. The lockout
+// starts at LockoutBase and doubles with every further failure up to LockoutMax.
+type LoginConfig struct {
+	MaxFailuresPerEmail int64         `mapstructure:"max-failures-per-email"`
+	MaxFailuresPerIP    int64         `mapstructure:"max-failures-per-ip"`
+	Window              time.Duration `mapstructure:"window"`
+	LockoutBase         time.Duration `mapstructure:"lockout-base"`
+	LockoutMax          time.Duration `mapstructure:"lockout-max"`
+}
+
+// PasswordResetConfig URL is the page mailed to the user, %s is replaced by the reset token. Another reset
+// can't be requested for the same email within Cooldown.
+type PasswordResetConfig struct {
+	URL      string        `mapstructure:"url"`
+	TokenTTL time.Duration `mapstructure:"token-ttl"`
+	Cooldown time.Duration `mapstructure:"cooldown"`
+}
+
+// EmailVerificationConfig URL is the page mailed to new accounts, %s is replaced by the verification token.
+// RestrictLogin and RestrictViewProfile reject unverified accounts at those steps.
+type EmailVerificationConfig struct {
+	URL                 string        `mapstructure:"url"`
+	TokenTTL            time.Duration `mapstructure:"token-ttl"`
+	Cooldown            time.Duration `mapstructure:"cooldown"`
+	RestrictLogin       bool          `mapstructure:"restrict-login"`
+	RestrictViewProfile bool          `mapstructure:"restrict-view-profile"`
+}
+
+// WebsocketConfig a ticket opens one websocket within TicketTTL. Browsers may only connect from AllowedOrigins,
+// "*" allows any, clients that send no Origin such as mobile apps are always allowed. A ping event is sent every
+// PingInterval and the connection is closed when the client sends nothing, e.g. the pong, within PongWait or
+// a write takes longer than WriteWait.
+type WebsocketConfig struct {
+	TicketTTL      time.Duration `mapstructure:"ticket-ttl"`
+	AllowedOrigins []string      `mapstructure:"allowed-origins"`
+	PingInterval   time.Duration `mapstructure:"ping-interval"`
+	PongWait       time.Duration `mapstructure:"pong-wait"`
+	WriteWait      time.Duration `mapstructure:"write-wait"`
+}
+
+// MailConfig Driver is one of smtp, file or memory. The file driver appends mails to FilePath instead of sending them.
+type MailConfig struct {
+	Driver   string `mapstructure:"driver"`
+	From     string `mapstructure:"from"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	FilePath string `mapstructure:"file-path"`
+}
+
 func GetConfig() *Config {
 	v := viper.New()
 	v.SetConfigType("yaml")
@@ -42,6 +229,73 @@ func GetConfig() *Config {
 	v.AddConfigPath("./config")
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
 	v.AutomaticEnv()
+	v.SetDefault("server.request-timeout", "10s")
+	v.SetDefault("server.shutdown-timeout", "10s")
+	v.SetDefault("user-server.keepalive-time", "5m")
+	v.SetDefault("user-server.keepalive-timeout", "10s")
+	v.SetDefault("auth-server.keepalive-time", "5m")
+	v.SetDefault("auth-server.keepalive-timeout", "10s")
+	v.SetDefault("user-server.load-balancing", "round_robin")
+	v.SetDefault("user-server.health-check", true)
+	v.SetDefault("user-server.deadline", "3s")
+	v.SetDefault("user-server.retry.max-attempts", 3)
+	v.SetDefault("user-server.retry.base-delay", "50ms")
+	v.SetDefault("user-server.retry.max-delay", "500ms")
+	v.SetDefault("user-server.circuit-breaker.failure-threshold", 5)
+	v.SetDefault("user-server.circuit-breaker.open-timeout", "30s")
+	v.SetDefault("user-server.tls.reload-interval", "1m")
+	v.SetDefault("auth-server.load-balancing", "round_robin")
+	v.SetDefault("auth-server.health-check", true)
+	v.SetDefault("auth-server.deadline", "3s")
+	v.SetDefault("auth-server.retry.max-attempts", 3)
+	v.SetDefault("auth-server.retry.base-delay", "50ms")
+	v.SetDefault("auth-server.retry.max-delay", "500ms")
+	v.SetDefault("auth-server.circuit-breaker.failure-threshold", 5)
+	v.SetDefault("auth-server.circuit-breaker.open-timeout", "30s")
+	v.SetDefault("auth-server.tls.reload-interval", "1m")
+	v.SetDefault("quota.daily-swipe-limit", 10)
+	v.SetDefault("quota.default-timezone", "Asia/Jakarta")
+	v.SetDefault("history.retention", "720h")
+	v.SetDefault("history.max-exclude-ids", 500)
+	v.SetDefault("worker.poll-interval", "1s")
+	v.SetDefault("worker.batch-size", 100)
+	v.SetDefault("worker.max-attempts", 5)
+	v.SetDefault("worker.retry-delay", "1m")
+	v.SetDefault("worker.lease", "5m")
+	v.SetDefault("payment.processing-timeout", "5m")
+	v.SetDefault("idempotency.lock-ttl", "1m")
+	v.SetDefault("idempotency.ttl", "24h")
+	v.SetDefault("auth.token-cache-ttl", "1m")
+	v.SetDefault("auth.max-token-lifetime", "168h")
+	v.SetDefault("auth.refresh-token-ttl", "720h")
+	v.SetDefault("login.max-failures-per-email", 5)
+	v.SetDefault("login.max-failures-per-ip", 20)
+	v.SetDefault("login.window", "15m")
+	v.SetDefault("login.lockout-base", "1m")
+	v.SetDefault("login.lockout-max", "1h")
+	v.SetDefault("password-reset.token-ttl", "30m")
+	v.SetDefault("password-reset.cooldown", "1m")
+	v.SetDefault("email-verification.token-ttl", "48h")
+	v.SetDefault("email-verification.cooldown", "1m")
+	v.SetDefault("email-verification.restrict-login", false)
+	v.SetDefault("email-verification.restrict-view-profile", true)
+	v.SetDefault("websocket.ticket-ttl", "30s")
+	v.SetDefault("websocket.ping-interval", "30s")
+	v.SetDefault("websocket.pong-wait", "75s")
+	v.SetDefault("websocket.write-wait", "10s")
+	v.SetDefault("mail.driver", "file")
+	v.SetDefault("mail.file-path", "mails.log")
+	v.SetDefault("mail.host", "")
+	v.SetDefault("mail.port", 587)
+	v.SetDefault("mail.username", "")
+	v.SetDefault("mail.password", "")
+	v.SetDefault("auth.jwt.public-key-file", "")
+	v.SetDefault("auth.jwt.jwks-file", "")
+	v.SetDefault("auth.jwt.jwks-url", "")
+	v.SetDefault("auth.jwt.issuer", "")
+	v.SetDefault("auth.jwt.audience", "")
+	v.SetDefault("auth.jwt.email-claim", "email")
+	v.SetDefault("auth.jwt.check-revocation", false)
 
 	err := v.ReadInConfig()
 	if err != nil {
@@ -53,6 +307,12 @@ func GetConfig() *Config {
 	if err != nil {
 		panic(fmt.Errorf("Fatal error config file: %s \n", err))
 	}
+	if cfg.HistoryConfig.MaxExcludeIDs <= 0 {
+		panic(fmt.Errorf("Fatal error config file: history.max-exclude-ids must be greater than 0, got %d \n", cfg.HistoryConfig.MaxExcludeIDs))
+	}
+	if cfg.IdempotencyConfig.LockTTL <= cfg.ServerConfig.RequestTimeout {
+		panic(fmt.Errorf("Fatal error config file: idempotency.lock-ttl must be greater than server.request-timeout, got %s and %s \n", cfg.IdempotencyConfig.LockTTL, cfg.ServerConfig.RequestTimeout))
+	}
 
 	return &cfg
 }
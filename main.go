@@ -1,6 +1,8 @@
 package main
 
 import (
+	_ "time/tzdata"
+
 	"github.com/atrariksa/kenalan-core/app/handler"
 )
 